@@ -0,0 +1,101 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AccessTokenInfo is what Google's tokeninfo endpoint reports about an OAuth2 access token
+type AccessTokenInfo struct {
+	Audience        string
+	AuthorizedParty string
+	Subject         string
+	Email           string
+	EmailVerified   bool
+	Scopes          []string
+	Expiry          time.Time
+}
+
+// HasScope reports whether the access token was granted scope
+func (i *AccessTokenInfo) HasScope(scope string) bool {
+	return contains(i.Scopes, scope)
+}
+
+type accessTokenInfo struct {
+	Aud              string `json:"aud"`
+	Azp              string `json:"azp"`
+	Sub              string `json:"sub"`
+	Email            string `json:"email"`
+	EmailVerified    string `json:"email_verified"`
+	Scope            string `json:"scope"`
+	Exp              string `json:"exp"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// VerifyAccessToken checks an OAuth2 access token with Google's tokeninfo endpoint, as access tokens
+// cannot be verified locally, and that it was issued for one of the audiences, or else the DefaultAudience.
+// Tokens rejected by tokeninfo return ErrInvalidAccessToken.
+func (v *CertsVerifier) VerifyAccessToken(ctx context.Context, accessToken string, audience ...string) (*AccessTokenInfo, error) {
+	if len(audience) == 0 {
+		audience = v.DefaultAudience
+	}
+	req, err := http.NewRequest(http.MethodGet, googleTokenInfoURL+"?"+url.Values{"access_token": {accessToken}}.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	client := v.HTTPClient
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	res := &accessTokenInfo{}
+	err = json.NewDecoder(resp.Body).Decode(res)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK || res.Error != "" {
+		return nil, fmt.Errorf("%w: %s %s", ErrInvalidAccessToken, res.Error, res.ErrorDescription)
+	}
+
+	info := &AccessTokenInfo{
+		Audience:        res.Aud,
+		AuthorizedParty: res.Azp,
+		Subject:         res.Sub,
+		Email:           res.Email,
+		EmailVerified:   res.EmailVerified == "true",
+		Scopes:          strings.Fields(res.Scope),
+	}
+	if exp, err := strconv.ParseInt(res.Exp, 10, 64); err == nil {
+		info.Expiry = time.Unix(exp, 0)
+	}
+	if !contains(audience, info.Audience) {
+		return nil, fmt.Errorf("%w: %s", ErrWrongAudience, info.Audience)
+	}
+	return info, nil
+}
+
+// CheckAccessTokenHash checks the at_hash claim of a verified ID token matches the access token
+// obtained with it, so both are known to come from the same authentication
+func CheckAccessTokenHash(claimSet *ClaimSet, accessToken string) error {
+	h := sha256.Sum256([]byte(accessToken))
+	expected := base64.RawURLEncoding.EncodeToString(h[:len(h)/2])
+	if claimSet.AccessTokenHash == "" || subtle.ConstantTimeCompare([]byte(claimSet.AccessTokenHash), []byte(expected)) != 1 {
+		return ErrWrongAccessTokenHash
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerifyAccessToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("access_token") != "ya29.valid" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": "invalid_token", "error_description": "Invalid Value"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"aud": "client-id", "sub": "1", "scope": "openid https://www.googleapis.com/auth/userinfo.email",
+			"exp": "1600000000", "email_verified": "true"}`))
+	}))
+	defer srv.Close()
+	defer func(url string) { googleTokenInfoURL = url }(googleTokenInfoURL)
+	googleTokenInfoURL = srv.URL
+
+	v := NewVerifier(WithAudiences("client-id"))
+	ctx := context.Background()
+	info, err := v.VerifyAccessToken(ctx, "ya29.valid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.HasScope("openid") || !info.EmailVerified || info.Expiry.Unix() != 1600000000 {
+		t.Errorf("Unexpected token info %+v", info)
+	}
+	if _, err = v.VerifyAccessToken(ctx, "ya29.valid", "other"); !errors.Is(err, ErrWrongAudience) {
+		t.Errorf("Expect ErrWrongAudience, got %v", err)
+	}
+	if _, err = v.VerifyAccessToken(ctx, "ya29.revoked"); !errors.Is(err, ErrInvalidAccessToken) {
+		t.Errorf("Expect ErrInvalidAccessToken, got %v", err)
+	}
+}
+
+func TestCheckAccessTokenHash(t *testing.T) {
+	h := sha256.Sum256([]byte("ya29.valid"))
+	claimSet := &ClaimSet{AccessTokenHash: base64.RawURLEncoding.EncodeToString(h[:16])}
+	if err := CheckAccessTokenHash(claimSet, "ya29.valid"); err != nil {
+		t.Error(err)
+	}
+	if err := CheckAccessTokenHash(claimSet, "ya29.other"); err != ErrWrongAccessTokenHash {
+		t.Errorf("Expect ErrWrongAccessTokenHash, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+// Package echoauth protects Echo routes with Google ID tokens.
+//
+//	auth := echoauth.New(verifier, clientID)
+//	e.GET("/api/me", me, auth.Middleware())
+//	e.GET("/admin", admin, auth.WithAudience(adminClientID).Middleware())
+//	// in me:
+//	claimSet, _ := echoauth.Claims(c)
+package echoauth
+
+import (
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+	"github.com/fafg/google-id-verifier/adapters/internal/routeauth"
+	"github.com/fafg/google-id-verifier/httpmiddleware"
+	"github.com/labstack/echo/v4"
+)
+
+// ClaimsKey is the key of the verified claims in the echo.Context
+const ClaimsKey = "googleIDVerifier.claims"
+
+// Auth rejects requests without a valid ID token
+type Auth struct {
+	Verifier httpmiddleware.Verifier
+	// Audience is passed to the verifier, which uses its default audiences when empty
+	Audience []string
+	// Issuers, if set, are accepted instead of the verifier issuers. The verifier must support it,
+	// like googleIDVerifier.CertsVerifier does, or every request is rejected.
+	Issuers []string
+	// Extractor finds the token in requests, googleIDVerifier.BearerExtractor when nil
+	Extractor googleIDVerifier.TokenExtractor
+	// FailureLogger, if set, logs the rejections with a rate limit per error code
+	FailureLogger *googleIDVerifier.FailureLogger
+	// OnError, if set, returns the error of rejected requests for the Echo error handler instead of
+	// responding a 401, or 403 for users not allowed, with client-safe problem details and a Bearer challenge
+	OnError func(c echo.Context, err error) error
+}
+
+// New returns an Auth verifying tokens with v for the given audiences
+func New(v httpmiddleware.Verifier, audience ...string) *Auth {
+	return &Auth{Verifier: v, Audience: audience}
+}
+
+// WithAudience returns a copy of a for routes accepting other audiences
+func (a *Auth) WithAudience(audience ...string) *Auth {
+	route := *a
+	route.Audience = audience
+	return &route
+}
+
+// WithIssuers returns a copy of a for routes accepting other issuers
+func (a *Auth) WithIssuers(issuers ...string) *Auth {
+	route := *a
+	route.Issuers = issuers
+	return &route
+}
+
+// Middleware verifies the token of each request and stores its claims in the echo.Context and the
+// request context, see Claims and googleIDVerifier.ClaimsFromContext
+func (a *Auth) Middleware() echo.MiddlewareFunc {
+	route := &routeauth.Route{Verifier: a.Verifier, Audience: a.Audience, Issuers: a.Issuers, Extractor: a.Extractor,
+		FailureLogger: a.FailureLogger}
+	onError := a.OnError
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			r := c.Request()
+			claimSet, err := route.Verify(r.Context(), r.Header)
+			if err != nil {
+				if onError != nil {
+					return onError(c, err)
+				}
+				routeauth.Reject(c.Response(), err)
+				return nil
+			}
+			c.Set(ClaimsKey, claimSet)
+			c.SetRequest(r.WithContext(googleIDVerifier.ContextWithClaims(r.Context(), claimSet)))
+			return next(c)
+		}
+	}
+}
+
+// Claims returns the claims stored by the middleware, if any
+func Claims(c echo.Context) (*googleIDVerifier.ClaimSet, bool) {
+	claimSet, ok := c.Get(ClaimsKey).(*googleIDVerifier.ClaimSet)
+	return claimSet, ok
+}
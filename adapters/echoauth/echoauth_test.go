@@ -0,0 +1,69 @@
+package echoauth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+	"github.com/fafg/google-id-verifier/idptest"
+	"github.com/labstack/echo/v4"
+)
+
+func TestAuth(t *testing.T) {
+	idp, err := idptest.New("client-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idp.Close()
+	auth := New(googleIDVerifier.NewStaticVerifier(idp.Certs()), "client-id")
+	e := echo.New()
+	me := func(c echo.Context) error {
+		claimSet, ok := Claims(c)
+		if _, inRequest := googleIDVerifier.ClaimsFromContext(c.Request().Context()); !ok || !inRequest {
+			return errors.New("no claims in the echo and request contexts")
+		}
+		return c.String(http.StatusOK, claimSet.Email)
+	}
+	e.GET("/me", me, auth.Middleware())
+	e.GET("/admin", me, auth.WithAudience("admin-client").Middleware())
+	forbidden := auth.WithAudience("admin-client")
+	forbidden.OnError = func(c echo.Context, err error) error {
+		return echo.NewHTTPError(http.StatusForbidden, googleIDVerifier.ClientReason(err).Description())
+	}
+	e.GET("/custom", me, forbidden.Middleware())
+	staff := New(googleIDVerifier.NewStaticVerifier(idp.Certs(), googleIDVerifier.WithAllowedHostedDomains("example.org")), "client-id")
+	e.GET("/staff", me, staff.Middleware())
+
+	token, _ := idp.Token(nil)
+	adminToken, _ := idp.Token(map[string]interface{}{"aud": "admin-client"})
+	tests := []struct {
+		path  string
+		token string
+		code  int
+	}{
+		{"/me", token, http.StatusOK},
+		{"/me", "", http.StatusUnauthorized},
+		{"/admin", adminToken, http.StatusOK},
+		{"/admin", token, http.StatusUnauthorized},
+		{"/custom", token, http.StatusForbidden},
+		{"/staff", token, http.StatusForbidden},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != test.code {
+			t.Errorf("%s: expect %d, got %d %s", test.path, test.code, rec.Code, rec.Body.String())
+		}
+		if test.path != "/custom" && rec.Code != http.StatusOK && (!strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Bearer") ||
+			rec.Header().Get("Content-Type") != googleIDVerifier.ProblemContentType) {
+			t.Errorf("%s: expect a challenge and problem details, got %v", test.path, rec.Header())
+		}
+	}
+}
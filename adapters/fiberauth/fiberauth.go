@@ -0,0 +1,93 @@
+// Package fiberauth protects Fiber routes with Google ID tokens.
+//
+//	auth := fiberauth.New(verifier, clientID)
+//	app.Get("/api/me", auth.Handler(), me)
+//	app.Get("/admin", auth.WithAudience(adminClientID).Handler(), admin)
+//	// in me:
+//	claimSet, _ := fiberauth.Claims(c)
+package fiberauth
+
+import (
+	"encoding/json"
+	"net/http"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+	"github.com/fafg/google-id-verifier/adapters/internal/routeauth"
+	"github.com/fafg/google-id-verifier/httpmiddleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ClaimsKey is the key of the verified claims in the locals of the fiber.Ctx
+const ClaimsKey = "googleIDVerifier.claims"
+
+// Auth rejects requests without a valid ID token
+type Auth struct {
+	Verifier httpmiddleware.Verifier
+	// Audience is passed to the verifier, which uses its default audiences when empty
+	Audience []string
+	// Issuers, if set, are accepted instead of the verifier issuers. The verifier must support it,
+	// like googleIDVerifier.CertsVerifier does, or every request is rejected.
+	Issuers []string
+	// Extractor finds the token in requests, googleIDVerifier.BearerExtractor when nil
+	Extractor googleIDVerifier.TokenExtractor
+	// FailureLogger, if set, logs the rejections with a rate limit per error code
+	FailureLogger *googleIDVerifier.FailureLogger
+	// OnError, if set, responds to rejected requests instead of a 401, or 403 for users not allowed,
+	// with client-safe problem details and a Bearer challenge
+	OnError func(c *fiber.Ctx, err error) error
+}
+
+// New returns an Auth verifying tokens with v for the given audiences
+func New(v httpmiddleware.Verifier, audience ...string) *Auth {
+	return &Auth{Verifier: v, Audience: audience}
+}
+
+// WithAudience returns a copy of a for routes accepting other audiences
+func (a *Auth) WithAudience(audience ...string) *Auth {
+	route := *a
+	route.Audience = audience
+	return &route
+}
+
+// WithIssuers returns a copy of a for routes accepting other issuers
+func (a *Auth) WithIssuers(issuers ...string) *Auth {
+	route := *a
+	route.Issuers = issuers
+	return &route
+}
+
+// Handler verifies the token of each request and stores its claims in the locals, see Claims
+func (a *Auth) Handler() fiber.Handler {
+	route := &routeauth.Route{Verifier: a.Verifier, Audience: a.Audience, Issuers: a.Issuers, Extractor: a.Extractor,
+		FailureLogger: a.FailureLogger}
+	onError := a.OnError
+	return func(c *fiber.Ctx) error {
+		// fasthttp has no net/http headers for the extractors
+		header := http.Header{}
+		c.Request().Header.VisitAll(func(key, value []byte) {
+			header.Add(string(key), string(value))
+		})
+		claimSet, err := route.Verify(c.Context(), header)
+		if err != nil {
+			if onError != nil {
+				return onError(c, err)
+			}
+			c.Set("WWW-Authenticate", httpmiddleware.Challenge(err))
+			problem := routeauth.Rejection(err)
+			body, err := json.Marshal(problem)
+			if err != nil {
+				return err
+			}
+			c.Set(fiber.HeaderContentType, googleIDVerifier.ProblemContentType)
+			return c.Status(problem.Status).Send(body)
+		}
+		c.Locals(ClaimsKey, claimSet)
+		return c.Next()
+	}
+}
+
+// Claims returns the claims stored by the handler, if any
+func Claims(c *fiber.Ctx) (*googleIDVerifier.ClaimSet, bool) {
+	claimSet, ok := c.Locals(ClaimsKey).(*googleIDVerifier.ClaimSet)
+	return claimSet, ok
+}
@@ -0,0 +1,64 @@
+package fiberauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+	"github.com/fafg/google-id-verifier/idptest"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestAuth(t *testing.T) {
+	idp, err := idptest.New("client-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idp.Close()
+	auth := New(googleIDVerifier.NewStaticVerifier(idp.Certs()), "client-id")
+	app := fiber.New()
+	me := func(c *fiber.Ctx) error {
+		claimSet, ok := Claims(c)
+		if !ok {
+			return fiber.ErrInternalServerError
+		}
+		return c.SendString(claimSet.Email)
+	}
+	app.Get("/me", auth.Handler(), me)
+	app.Get("/admin", auth.WithAudience("admin-client").Handler(), me)
+	staff := New(googleIDVerifier.NewStaticVerifier(idp.Certs(), googleIDVerifier.WithAllowedHostedDomains("example.org")), "client-id")
+	app.Get("/staff", staff.Handler(), me)
+
+	token, _ := idp.Token(nil)
+	adminToken, _ := idp.Token(map[string]interface{}{"aud": "admin-client"})
+	tests := []struct {
+		path  string
+		token string
+		code  int
+	}{
+		{"/me", token, http.StatusOK},
+		{"/me", "", http.StatusUnauthorized},
+		{"/admin", adminToken, http.StatusOK},
+		{"/admin", token, http.StatusUnauthorized},
+		{"/staff", token, http.StatusForbidden},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != test.code {
+			t.Errorf("%s: expect %d, got %d", test.path, test.code, resp.StatusCode)
+		}
+		if resp.StatusCode != http.StatusOK && (!strings.HasPrefix(resp.Header.Get("WWW-Authenticate"), "Bearer") ||
+			resp.Header.Get("Content-Type") != googleIDVerifier.ProblemContentType) {
+			t.Errorf("%s: expect a challenge and problem details, got %v", test.path, resp.Header)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Package ginauth protects Gin routes with Google ID tokens.
+//
+//	auth := ginauth.New(verifier, clientID)
+//	r.GET("/api/me", auth.Handler(), me)
+//	r.GET("/admin", auth.WithAudience(adminClientID).Handler(), admin)
+//	// in me:
+//	claimSet, _ := ginauth.Claims(c)
+package ginauth
+
+import (
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+	"github.com/fafg/google-id-verifier/adapters/internal/routeauth"
+	"github.com/fafg/google-id-verifier/httpmiddleware"
+	"github.com/gin-gonic/gin"
+)
+
+// ClaimsKey is the key of the verified claims in the gin.Context
+const ClaimsKey = "googleIDVerifier.claims"
+
+// Middleware rejects requests without a valid ID token
+type Middleware struct {
+	Verifier httpmiddleware.Verifier
+	// Audience is passed to the verifier, which uses its default audiences when empty
+	Audience []string
+	// Issuers, if set, are accepted instead of the verifier issuers. The verifier must support it,
+	// like googleIDVerifier.CertsVerifier does, or every request is rejected.
+	Issuers []string
+	// Extractor finds the token in requests, googleIDVerifier.BearerExtractor when nil
+	Extractor googleIDVerifier.TokenExtractor
+	// FailureLogger, if set, logs the rejections with a rate limit per error code
+	FailureLogger *googleIDVerifier.FailureLogger
+	// OnError, if set, writes the response of rejected requests instead of a 401, or 403 for users
+	// not allowed, with client-safe problem details and a Bearer challenge. The request is aborted in any case.
+	OnError func(c *gin.Context, err error)
+}
+
+// New returns a Middleware verifying tokens with v for the given audiences
+func New(v httpmiddleware.Verifier, audience ...string) *Middleware {
+	return &Middleware{Verifier: v, Audience: audience}
+}
+
+// WithAudience returns a copy of the middleware for routes accepting other audiences
+func (m *Middleware) WithAudience(audience ...string) *Middleware {
+	route := *m
+	route.Audience = audience
+	return &route
+}
+
+// WithIssuers returns a copy of the middleware for routes accepting other issuers
+func (m *Middleware) WithIssuers(issuers ...string) *Middleware {
+	route := *m
+	route.Issuers = issuers
+	return &route
+}
+
+// Handler verifies the token of each request and stores its claims in the gin.Context and the
+// request context, see Claims and googleIDVerifier.ClaimsFromContext
+func (m *Middleware) Handler() gin.HandlerFunc {
+	route := &routeauth.Route{Verifier: m.Verifier, Audience: m.Audience, Issuers: m.Issuers, Extractor: m.Extractor,
+		FailureLogger: m.FailureLogger}
+	onError := m.OnError
+	return func(c *gin.Context) {
+		claimSet, err := route.Verify(c.Request.Context(), c.Request.Header)
+		if err != nil {
+			if onError != nil {
+				onError(c, err)
+				c.Abort()
+				return
+			}
+			c.Abort()
+			routeauth.Reject(c.Writer, err)
+			return
+		}
+		c.Set(ClaimsKey, claimSet)
+		c.Request = c.Request.WithContext(googleIDVerifier.ContextWithClaims(c.Request.Context(), claimSet))
+		c.Next()
+	}
+}
+
+// Claims returns the claims stored by the middleware, if any
+func Claims(c *gin.Context) (*googleIDVerifier.ClaimSet, bool) {
+	v, ok := c.Get(ClaimsKey)
+	if !ok {
+		return nil, false
+	}
+	claimSet, ok := v.(*googleIDVerifier.ClaimSet)
+	return claimSet, ok
+}
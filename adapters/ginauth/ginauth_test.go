@@ -0,0 +1,72 @@
+package ginauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+	"github.com/fafg/google-id-verifier/idptest"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMiddleware(t *testing.T) {
+	idp, err := idptest.New("client-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idp.Close()
+	gin.SetMode(gin.TestMode)
+	auth := New(googleIDVerifier.NewStaticVerifier(idp.Certs()), "client-id")
+	failures := googleIDVerifier.NewFailureLogger(func(string, ...interface{}) {})
+	auth.FailureLogger = failures
+	r := gin.New()
+	me := func(c *gin.Context) {
+		claimSet, ok := Claims(c)
+		if _, inRequest := googleIDVerifier.ClaimsFromContext(c.Request.Context()); !ok || !inRequest {
+			t.Error("Expect claims in the gin and request contexts")
+			return
+		}
+		c.String(http.StatusOK, claimSet.Email)
+	}
+	r.GET("/me", auth.Handler(), me)
+	r.GET("/admin", auth.WithAudience("admin-client").Handler(), me)
+	r.GET("/partner", auth.WithIssuers("https://partner.example.com").Handler(), me)
+	staff := New(googleIDVerifier.NewStaticVerifier(idp.Certs(), googleIDVerifier.WithAllowedHostedDomains("example.org")), "client-id")
+	r.GET("/staff", staff.Handler(), me)
+
+	token, _ := idp.Token(nil)
+	adminToken, _ := idp.Token(map[string]interface{}{"aud": "admin-client"})
+	tests := []struct {
+		path  string
+		token string
+		code  int
+	}{
+		{"/me", token, http.StatusOK},
+		{"/me", "", http.StatusUnauthorized},
+		{"/me", adminToken, http.StatusUnauthorized},
+		{"/admin", adminToken, http.StatusOK},
+		{"/admin", token, http.StatusUnauthorized},
+		{"/partner", token, http.StatusUnauthorized},
+		{"/staff", token, http.StatusForbidden},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != test.code {
+			t.Errorf("%s: expect %d, got %d %s", test.path, test.code, rec.Code, rec.Body.String())
+		}
+		if rec.Code != http.StatusOK && (!strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Bearer") ||
+			rec.Header().Get("Content-Type") != googleIDVerifier.ProblemContentType) {
+			t.Errorf("%s: expect a challenge and problem details, got %v", test.path, rec.Header())
+		}
+	}
+	if counts := failures.Counts(); counts[googleIDVerifier.ErrNoToken.Error()] != 1 || counts[googleIDVerifier.ErrWrongAudience.Error()] != 2 {
+		t.Errorf("Expect the rejections to be logged, got %v", counts)
+	}
+}
@@ -0,0 +1,69 @@
+// Package routeauth holds the token extraction and verification shared by the framework adapters
+package routeauth
+
+import (
+	"context"
+	"net/http"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+	"github.com/fafg/google-id-verifier/httpmiddleware"
+)
+
+// ErrIssuersNotSupported is returned when issuers are set for a verifier which cannot override its issuers
+var ErrIssuersNotSupported = httpmiddleware.ErrIssuersNotSupported
+
+// Route is the verification policy of a route
+type Route struct {
+	Verifier httpmiddleware.Verifier
+	// Audience is passed to the verifier, which uses its default audiences when empty
+	Audience []string
+	// Issuers, if set, are accepted instead of the verifier issuers
+	Issuers []string
+	// Extractor finds the token in requests, googleIDVerifier.BearerExtractor when nil
+	Extractor googleIDVerifier.TokenExtractor
+	// FailureLogger, if set, logs the rejections with a rate limit per error code
+	FailureLogger *googleIDVerifier.FailureLogger
+}
+
+// Verify extracts the token from header and verifies it
+func (r *Route) Verify(ctx context.Context, header http.Header) (*googleIDVerifier.ClaimSet, error) {
+	claimSet, err := r.verify(ctx, header)
+	if err != nil && r.FailureLogger != nil {
+		r.FailureLogger.Log(err)
+	}
+	return claimSet, err
+}
+
+func (r *Route) verify(ctx context.Context, header http.Header) (*googleIDVerifier.ClaimSet, error) {
+	extractor := r.Extractor
+	if extractor == nil {
+		extractor = googleIDVerifier.BearerExtractor()
+	}
+	token, err := extractor.ExtractToken(header)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.Issuers) > 0 {
+		iv, ok := r.Verifier.(httpmiddleware.IssuersVerifier)
+		if !ok {
+			return nil, ErrIssuersNotSupported
+		}
+		return iv.VerifyIDTokenWithIssuersContext(ctx, token, r.Issuers, r.Audience...)
+	}
+	if cv, ok := r.Verifier.(httpmiddleware.ContextVerifier); ok {
+		return cv.VerifyIDTokenContext(ctx, token, r.Audience...)
+	}
+	return r.Verifier.VerifyIDToken(token, r.Audience...)
+}
+
+// Rejection returns the problem details of a rejected request, see googleIDVerifier.WriteRejection
+func Rejection(err error) *googleIDVerifier.Problem {
+	return googleIDVerifier.ClientReason(err).Problem()
+}
+
+// Reject writes the default response of a rejected request: a 401, or 403 for users not allowed,
+// with the httpmiddleware.Challenge of err and client-safe problem details
+func Reject(w http.ResponseWriter, err error) {
+	w.Header().Set("WWW-Authenticate", httpmiddleware.Challenge(err))
+	googleIDVerifier.WriteRejection(w, err)
+}
@@ -0,0 +1,115 @@
+package googleIDVerifier
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// AnomalyKind identifies a suspicious verification pattern
+type AnomalyKind string
+
+const (
+	// AnomalyRepeatedExpired is a subject presenting expired tokens again and again
+	AnomalyRepeatedExpired AnomalyKind = "repeated_expired"
+	// AnomalyFutureIssueTime is a token issued far in the future
+	AnomalyFutureIssueTime AnomalyKind = "future_iat"
+	// AnomalySignatureFailureSpike is a burst of tokens with wrong signatures
+	AnomalySignatureFailureSpike AnomalyKind = "signature_failure_spike"
+)
+
+// Anomaly is a suspicious pattern seen by an AnomalyTracker
+type Anomaly struct {
+	Kind AnomalyKind
+	// Subject is the sub claim involved, empty for spikes
+	Subject string
+	// Count is the number of occurrences in the current window
+	Count int
+	Time  time.Time
+}
+
+// AnomalyDetector is notified of anomalies, e.g. by security tooling
+type AnomalyDetector interface {
+	Detect(a Anomaly)
+}
+
+// AnomalyTracker observes verification failures and notifies its subscribers of anomalies.
+// Counts are kept in fixed windows of Window duration.
+type AnomalyTracker struct {
+	Window time.Duration
+	// ExpiredThreshold is the number of expired tokens from one subject reported as an anomaly
+	ExpiredThreshold int
+	// SignatureFailureThreshold is the number of signature failures reported as a spike
+	SignatureFailureThreshold int
+	// FutureIssueTime is how far in the future an iat has to be to be reported
+	FutureIssueTime time.Duration
+
+	mu                sync.Mutex
+	subscribers       []AnomalyDetector
+	windowStart       time.Time
+	expired           map[string]int
+	signatureFailures int
+}
+
+// NewAnomalyTracker returns a tracker with one minute windows, reporting 5 expired tokens per subject,
+// 100 signature failures, and issue times more than an hour in the future
+func NewAnomalyTracker() *AnomalyTracker {
+	return &AnomalyTracker{
+		Window:                    time.Minute,
+		ExpiredThreshold:          5,
+		SignatureFailureThreshold: 100,
+		FutureIssueTime:           time.Hour,
+		expired:                   map[string]int{},
+	}
+}
+
+// Subscribe adds a detector notified of every anomaly
+func (t *AnomalyTracker) Subscribe(d AnomalyDetector) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.subscribers = append(t.subscribers, d)
+}
+
+// Observe records the outcome of a verification. Expired tokens are only counted for their subject
+// when signed reports their signature is valid, as anyone can forge an expired token for any sub.
+func (t *AnomalyTracker) Observe(idToken string, err error, signed bool) {
+	if err == nil {
+		return
+	}
+	now := nowFn()
+
+	t.mu.Lock()
+	if now.Sub(t.windowStart) >= t.Window {
+		t.windowStart = now
+		t.expired = map[string]int{}
+		t.signatureFailures = 0
+	}
+	var anomalies []Anomaly
+	if errors.Is(err, ErrWrongSignature) || errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.signatureFailures++
+		if t.signatureFailures == t.SignatureFailureThreshold {
+			anomalies = append(anomalies, Anomaly{Kind: AnomalySignatureFailureSpike, Count: t.signatureFailures, Time: now})
+		}
+	}
+	// the iat is looked at whatever the error: a token issued far in the future fails first
+	// with ErrExpirationTimeTooFarInFuture rather than ErrTokenUsedTooEarly
+	if claimSet, decodeErr := Decode(idToken); decodeErr == nil {
+		if signed && errors.Is(err, ErrTokenUsedTooLate) {
+			t.expired[claimSet.Sub]++
+			if count := t.expired[claimSet.Sub]; count == t.ExpiredThreshold {
+				anomalies = append(anomalies, Anomaly{Kind: AnomalyRepeatedExpired, Subject: claimSet.Sub, Count: count, Time: now})
+			}
+		}
+		if claimSet.Iat > now.Add(t.FutureIssueTime).Unix() {
+			anomalies = append(anomalies, Anomaly{Kind: AnomalyFutureIssueTime, Subject: claimSet.Sub, Count: 1, Time: now})
+		}
+	}
+	subscribers := t.subscribers
+	t.mu.Unlock()
+
+	for _, a := range anomalies {
+		for _, d := range subscribers {
+			d.Detect(a)
+		}
+	}
+}
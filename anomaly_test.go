@@ -0,0 +1,92 @@
+package googleIDVerifier
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+type anomalyRecorder []Anomaly
+
+func (r *anomalyRecorder) Detect(a Anomaly) {
+	*r = append(*r, a)
+}
+
+func TestAnomalyTracker(t *testing.T) {
+	tracker := NewAnomalyTracker()
+	tracker.ExpiredThreshold = 2
+	tracker.SignatureFailureThreshold = 3
+	recorder := &anomalyRecorder{}
+	tracker.Subscribe(recorder)
+
+	for i := 0; i < 3; i++ {
+		tracker.Observe(validTestToken, ErrTokenUsedTooLate, true)
+		tracker.Observe(wrongSigToken, ErrWrongSignature, false)
+	}
+	tracker.Observe(validTestToken, nil, false)
+
+	nowFn = func() time.Time {
+		return time.Unix(0, 0)
+	}
+	defer func() { nowFn = time.Now }()
+	tracker.Observe(validTestToken, ErrTokenUsedTooEarly, false)
+
+	kinds := map[AnomalyKind]int{}
+	for _, a := range *recorder {
+		kinds[a.Kind]++
+	}
+	if kinds[AnomalyRepeatedExpired] != 1 || kinds[AnomalySignatureFailureSpike] != 1 || kinds[AnomalyFutureIssueTime] != 1 {
+		t.Errorf("Expect each anomaly to be reported once, got %+v", *recorder)
+	}
+	if (*recorder)[0].Subject != "114160320275393755424" {
+		t.Errorf("Expect expired anomaly to name the subject, got %+v", (*recorder)[0])
+	}
+}
+
+func TestAnomalyTrackerFarFutureIssueTime(t *testing.T) {
+	tracker := NewAnomalyTracker()
+	recorder := &anomalyRecorder{}
+	tracker.Subscribe(recorder)
+
+	iat := time.Now().Add(30 * 24 * time.Hour).Unix()
+	claims, _ := json.Marshal(map[string]interface{}{"sub": "future", "iat": iat, "exp": iat + 3600})
+	token := "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(claims) + ".sig"
+	certs, _ := getTestCerts()
+	_, err := VerifySignedJWTWithCerts(token, certs, nil, Issuers, MaxTokenLifetime)
+	if !errors.Is(err, ErrExpirationTimeTooFarInFuture) {
+		t.Fatalf("Expect ErrExpirationTimeTooFarInFuture, got %v", err)
+	}
+	tracker.Observe(token, err, false)
+	if len(*recorder) != 1 || (*recorder)[0].Kind != AnomalyFutureIssueTime || (*recorder)[0].Subject != "future" {
+		t.Errorf("Expect the future iat to be reported, got %+v", *recorder)
+	}
+}
+
+func TestAnomalyTrackerForgedExpired(t *testing.T) {
+	certs, _ := getTestCerts()
+	_, claimSet, _ := parseJWT(validTestToken)
+	tracker := NewAnomalyTracker()
+	tracker.ExpiredThreshold = 2
+	recorder := &anomalyRecorder{}
+	tracker.Subscribe(recorder)
+	v := NewStaticVerifier(certs, WithAudiences(claimSet.Aud))
+	v.Anomalies = tracker
+
+	for i := 0; i < 3; i++ {
+		if _, err := v.VerifyIDToken(wrongSigToken); !errors.Is(err, ErrTokenUsedTooLate) {
+			t.Fatalf("Expect ErrTokenUsedTooLate, got %v", err)
+		}
+	}
+	if len(*recorder) != 0 {
+		t.Errorf("Expect expired tokens with wrong signatures not to be counted, got %+v", *recorder)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, _ = v.VerifyIDToken(validTestToken)
+	}
+	if len(*recorder) != 1 || (*recorder)[0].Kind != AnomalyRepeatedExpired {
+		t.Errorf("Expect the signed expired tokens to be reported, got %+v", *recorder)
+	}
+}
@@ -0,0 +1,82 @@
+package googleIDVerifier
+
+import "fmt"
+
+// ClaimAssertion chains checks on verified claims, keeping the first failure:
+//
+//	err := Claims(claimSet).RequireAudience(aud).RequireHostedDomain("example.com").RequireEmailVerified().Err()
+type ClaimAssertion struct {
+	claimSet *ClaimSet
+	err      error
+}
+
+// Claims starts an assertion chain on the claims
+func Claims(claimSet *ClaimSet) *ClaimAssertion {
+	return &ClaimAssertion{claimSet: claimSet}
+}
+
+// Require runs a custom check
+func (a *ClaimAssertion) Require(check func(claimSet *ClaimSet) error) *ClaimAssertion {
+	if a.err == nil {
+		a.err = check(a.claimSet)
+	}
+	return a
+}
+
+// RequireAudience checks the aud claim is one of the given audiences
+func (a *ClaimAssertion) RequireAudience(audiences ...string) *ClaimAssertion {
+	return a.Require(func(claimSet *ClaimSet) error {
+		return checkAudiences(claimSet, audiences)
+	})
+}
+
+// RequireIssuer checks the iss claim is one of the given issuers
+func (a *ClaimAssertion) RequireIssuer(issuers ...string) *ClaimAssertion {
+	return a.Require(func(claimSet *ClaimSet) error {
+		return checkIssuer(claimSet, issuers)
+	})
+}
+
+// RequireHostedDomain checks the hd claim is one of the given Google Workspace domains
+func (a *ClaimAssertion) RequireHostedDomain(domains ...string) *ClaimAssertion {
+	return a.Require(func(claimSet *ClaimSet) error {
+		if !contains(domains, claimSet.HostedDomain) {
+			return fmt.Errorf("%w: %q", ErrHostedDomainNotAllowed, claimSet.HostedDomain)
+		}
+		return nil
+	})
+}
+
+// RequireEmailVerified checks the email_verified claim is true
+func (a *ClaimAssertion) RequireEmailVerified() *ClaimAssertion {
+	return a.Require(func(claimSet *ClaimSet) error {
+		if !claimSet.EmailVerified {
+			return ErrEmailNotVerified
+		}
+		return nil
+	})
+}
+
+// RequireEmail checks the email claim is one of the given addresses
+func (a *ClaimAssertion) RequireEmail(emails ...string) *ClaimAssertion {
+	return a.Require(func(claimSet *ClaimSet) error {
+		if !contains(emails, claimSet.Email) {
+			return fmt.Errorf("%w: %s", ErrEmailNotAllowed, claimSet.Email)
+		}
+		return nil
+	})
+}
+
+// Err returns the first failed check, nil when all passed
+func (a *ClaimAssertion) Err() error {
+	return a.err
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package googleIDVerifier
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClaimAssertion(t *testing.T) {
+	_, claimSet, _ := parseJWT(validTestToken)
+
+	err := Claims(claimSet).RequireAudience(claimSet.Aud).RequireIssuer(Issuers...).
+		RequireEmailVerified().RequireEmail("plutonio@gmail.com").Err()
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = Claims(claimSet).RequireHostedDomain("example.com").RequireAudience("other").Err()
+	if !errors.Is(err, ErrHostedDomainNotAllowed) {
+		t.Errorf("Expect first failure ErrHostedDomainNotAllowed, got %v", err)
+	}
+
+	certs, _ := getTestCerts()
+	defer func(keySet *KeySet) { googleKeySet = keySet }(googleKeySet)
+	googleKeySet = NewKeySet(certs, nil)
+	nowFn = func() time.Time {
+		return time.Unix(claimSet.Exp, 0)
+	}
+	defer func() { nowFn = time.Now }()
+
+	v := &CertsVerifier{Assert: func(c *ClaimAssertion) *ClaimAssertion {
+		return c.RequireEmail("someone@example.com")
+	}}
+	_, err = v.VerifyIDToken(validTestToken, claimSet.Aud)
+	if !errors.Is(err, ErrEmailNotAllowed) {
+		t.Errorf("Expect ErrEmailNotAllowed, got %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package googleIDVerifier
+
+import (
+	"path"
+	"strings"
+)
+
+// AudiencePrefix returns an audience matcher accepting the audiences starting with prefix,
+// e.g. "https://api.example.com/" for every path of a service
+func AudiencePrefix(prefix string) func(aud string) bool {
+	return func(aud string) bool {
+		return strings.HasPrefix(aud, prefix)
+	}
+}
+
+// AudienceGlob returns an audience matcher accepting the audiences matching pattern with the syntax
+// of path.Match, where * does not match /, e.g. "https://*.example.com" for every host of a domain.
+// A malformed pattern matches nothing.
+func AudienceGlob(pattern string) func(aud string) bool {
+	return func(aud string) bool {
+		matched, err := path.Match(pattern, aud)
+		return err == nil && matched
+	}
+}
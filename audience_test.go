@@ -0,0 +1,74 @@
+package googleIDVerifier
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2/jws"
+)
+
+func TestAudienceMatchers(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certs := &Certs{ECKeys: map[string]*ecdsa.PublicKey{"ec": &priv.PublicKey}}
+	now := time.Now()
+	token := func(aud interface{}) string {
+		return signES256(t, priv, "ec", map[string]interface{}{
+			"iss": "https://accounts.google.com", "aud": aud, "sub": "1", "iat": now.Unix(), "exp": now.Add(time.Hour).Unix(),
+		})
+	}
+
+	claimSet, err := NewStaticVerifier(certs, WithAllowedAlgorithms(es256)).VerifyIDToken(token([]string{"other", "client-id"}), "client-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimSet.Aud != "other" || len(claimSet.Audiences) != 2 {
+		t.Errorf("Expect aud other among 2 audiences, got %s, %v", claimSet.Aud, claimSet.Audiences)
+	}
+	data, err := json.Marshal(claimSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &ClaimSet{}
+	if err = json.Unmarshal(data, decoded); err != nil || len(decoded.Audiences) != 2 || decoded.Audiences[1] != "client-id" {
+		t.Errorf("Expect the audiences to survive a JSON round trip, got %s", data)
+	}
+	if data, _ = json.Marshal(&ClaimSet{ClaimSet: jws.ClaimSet{Aud: "client-id"}}); !strings.Contains(string(data), `"aud":"client-id"`) {
+		t.Errorf("Expect a single audience to be a string, got %s", data)
+	}
+
+	v := NewStaticVerifier(certs, WithAllowedAlgorithms(es256), WithAudienceMatcher(AudiencePrefix("https://api.example.com/")),
+		WithAudienceMatcher(AudienceGlob("https://*.run.app")))
+	tests := []struct {
+		aud   interface{}
+		valid bool
+	}{
+		{"https://api.example.com/users", true},
+		{"https://api.example.com.evil.com/users", false},
+		{"https://my-service-abc123.run.app", true},
+		{"https://my-service.run.app/path", false},
+		{[]string{"other", "https://svc.run.app"}, true},
+		{[]string{}, false},
+	}
+	for _, test := range tests {
+		_, err = v.VerifyIDToken(token(test.aud))
+		if test.valid && err != nil {
+			t.Errorf("Expect %v accepted, got %v", test.aud, err)
+		}
+		if !test.valid && !errors.Is(err, ErrWrongAudience) {
+			t.Errorf("Expect ErrWrongAudience for %v, got %v", test.aud, err)
+		}
+	}
+
+	if AudienceGlob("[")("[") {
+		t.Error("Expect a malformed pattern to match nothing")
+	}
+}
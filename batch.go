@@ -0,0 +1,57 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// Result is the outcome of the verification of one token of a batch
+type Result struct {
+	ClaimSet *ClaimSet
+	Err      error
+}
+
+// VerifyIDTokens verifies a batch of tokens, e.g. read from a message queue, across GOMAXPROCS workers
+// and returns their results in the order of tokens. The keys are looked up once for the whole batch:
+// when they cannot be fetched every result has the fetch error. Tokens not verified before ctx is
+// done get its error.
+func (v *CertsVerifier) VerifyIDTokens(ctx context.Context, tokens []string, audience ...string) []Result {
+	results := make([]Result, len(tokens))
+	if len(tokens) == 0 {
+		return results
+	}
+	if _, err := v.keySet().current(ctx); err != nil {
+		for i, token := range tokens {
+			results[i].Err = newVerificationError(token, err)
+		}
+		return results
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(tokens) {
+		workers = len(tokens)
+	}
+	issuers := v.issuers()
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := ctx.Err(); err != nil {
+					results[i].Err = err
+					continue
+				}
+				results[i].ClaimSet, results[i].Err = v.VerifyIDTokenWithIssuersContext(ctx, tokens[i], issuers, audience...)
+			}
+		}()
+	}
+	for i := range tokens {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
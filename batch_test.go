@@ -0,0 +1,82 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestVerifyIDTokens(t *testing.T) {
+	certs, _ := getTestCerts()
+	var fetches int32
+	v := NewVerifier(WithKeySet(NewKeySet(nil, func() (*Certs, error) {
+		atomic.AddInt32(&fetches, 1)
+		return certs, nil
+	})))
+	claimSet, _ := Decode(validTestToken)
+	nowFn = func() time.Time { return time.Unix(claimSet.Exp, 0) }
+	defer func() { nowFn = time.Now }()
+
+	tokens := []string{validTestToken, "garbage", wrongSigToken}
+	for i := 0; i < 100; i++ {
+		tokens = append(tokens, validTestToken)
+	}
+	results := v.VerifyIDTokens(context.Background(), tokens, claimSet.Aud)
+	if len(results) != len(tokens) {
+		t.Fatalf("Expect %d results, got %d", len(tokens), len(results))
+	}
+	if results[0].Err != nil || results[0].ClaimSet.Sub != claimSet.Sub {
+		t.Errorf("Expect first token valid, got %v", results[0].Err)
+	}
+	if !errors.Is(results[1].Err, ErrInvalidToken) || !errors.Is(results[2].Err, ErrWrongSignature) {
+		t.Errorf("Unexpected errors %v, %v", results[1].Err, results[2].Err)
+	}
+	for _, result := range results[3:] {
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("Expect the keys fetched once, got %d", fetches)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, result := range v.VerifyIDTokens(ctx, tokens, claimSet.Aud) {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Fatalf("Expect context.Canceled, got %v", result.Err)
+		}
+	}
+}
+
+func BenchmarkVerifyIDTokens(b *testing.B) {
+	certs, _ := getTestCerts()
+	v := NewStaticVerifier(certs)
+	claimSet, _ := Decode(validTestToken)
+	nowFn = func() time.Time { return time.Unix(claimSet.Exp, 0) }
+	defer func() { nowFn = time.Now }()
+	tokens := make([]string, 1000)
+	for i := range tokens {
+		tokens[i] = validTestToken
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v.VerifyIDTokens(context.Background(), tokens, claimSet.Aud)
+	}
+}
+
+func BenchmarkVerifyIDTokenLoop(b *testing.B) {
+	certs, _ := getTestCerts()
+	v := NewStaticVerifier(certs)
+	claimSet, _ := Decode(validTestToken)
+	nowFn = func() time.Time { return time.Unix(claimSet.Exp, 0) }
+	defer func() { nowFn = time.Now }()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 1000; j++ {
+			_, _ = v.VerifyIDToken(validTestToken, claimSet.Aud)
+		}
+	}
+}
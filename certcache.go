@@ -0,0 +1,82 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CertCache stores fetched keys so several processes share them, e.g. a fleet of serverless
+// instances which would otherwise fetch the Google certs on every cold start. The certcache/redis
+// package implements it with Redis.
+type CertCache interface {
+	// Get returns the value stored for key, or nil when it is missing or expired
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value for key during ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// MemoryCertCache is an in-memory CertCache, suitable for a single process
+type MemoryCertCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryCertCacheEntry
+}
+
+type memoryCertCacheEntry struct {
+	value  []byte
+	expiry time.Time
+}
+
+// NewMemoryCertCache returns an empty MemoryCertCache
+func NewMemoryCertCache() *MemoryCertCache {
+	return &MemoryCertCache{entries: map[string]memoryCertCacheEntry{}}
+}
+
+// Get returns the value stored for key unless expired
+func (c *MemoryCertCache) Get(_ context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !time.Now().Before(entry.expiry) {
+		delete(c.entries, key)
+		return nil, nil
+	}
+	return entry.value, nil
+}
+
+// Set stores value for key during ttl
+func (c *MemoryCertCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = memoryCertCacheEntry{value: value, expiry: time.Now().Add(ttl)}
+	return nil
+}
+
+// CachedKeySource returns a source serving the keys stored in cache under key while they are not
+// expired, and otherwise fetching them from source and storing them until their expiry.
+// Cache errors are ignored, the keys being fetched from source instead.
+func CachedKeySource(cache CertCache, key string, source KeySource) KeySource {
+	return KeySourceFunc(func(ctx context.Context) (*Certs, error) {
+		data, err := cache.Get(ctx, key)
+		if err == nil && data != nil {
+			certs := &Certs{}
+			if certs.UnmarshalJSON(data) == nil && time.Now().Before(certs.Expiry) {
+				return certs, nil
+			}
+		}
+		certs, err := source.Keys(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if ttl := time.Until(certs.Expiry); ttl > 0 {
+			if data, err = certs.MarshalJSON(); err == nil {
+				_ = cache.Set(ctx, key, data, ttl)
+			}
+		}
+		return certs, nil
+	})
+}
+
+func certCacheKey(url string) string {
+	return "google-id-verifier:certs:" + url
+}
@@ -0,0 +1,173 @@
+// Package redis implements googleIDVerifier.CertCache with a Redis server, so a fleet of instances
+// shares the fetched Google certs:
+//
+//	cache := redis.New("10.0.0.3:6379")
+//	v := googleIDVerifier.NewVerifier(googleIDVerifier.WithCertCache(cache))
+//
+// It speaks the Redis protocol directly over a single connection, which is plenty for the
+// few commands sent when the keys expire.
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Cache implements googleIDVerifier.CertCache with GET and SET commands
+type Cache struct {
+	Addr string
+	// Password, if set, authenticates the connection with AUTH
+	Password string
+	// Timeout bounds each command when the context has no earlier deadline, 2 seconds when zero
+	Timeout time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// New returns a Cache for the Redis server at addr, connecting on first use
+func New(addr string) *Cache {
+	return &Cache{Addr: addr}
+}
+
+// Get returns the value of key, or nil when missing
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, error) {
+	return c.do(ctx, "GET", key)
+}
+
+// Set stores value for key, expiring after ttl
+func (c *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := c.do(ctx, "SET", key, string(value), "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+// Close closes the connection, a later command reconnecting
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *Cache) do(ctx context.Context, args ...string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	deadline := c.deadline(ctx)
+	if c.conn == nil {
+		err := c.connect(ctx, deadline)
+		if err != nil {
+			return nil, err
+		}
+	}
+	reply, err := c.command(deadline, args...)
+	if err != nil {
+		var redisErr redisError
+		if !errors.As(err, &redisErr) {
+			// the connection state is unknown, so start over with a new one
+			_ = c.conn.Close()
+			c.conn = nil
+		}
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (c *Cache) deadline(ctx context.Context) time.Time {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 2 * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	return deadline
+}
+
+func (c *Cache) connect(ctx context.Context, deadline time.Time) error {
+	dialer := &net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", c.Addr)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+	if c.Password != "" {
+		_, err = c.command(deadline, "AUTH", c.Password)
+		if err != nil {
+			_ = conn.Close()
+			c.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// redisError is an error reply of the server, which leaves the connection usable
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// command sends args as an array of bulk strings and reads the reply
+func (c *Cache) command(deadline time.Time, args ...string) ([]byte, error) {
+	err := c.conn.SetDeadline(deadline)
+	if err != nil {
+		return nil, err
+	}
+	req := fmt.Sprintf("*%d\r\n", len(args))
+	for _, arg := range args {
+		req += fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg)
+	}
+	_, err = io.WriteString(c.conn, req)
+	if err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// readReply reads a simple string, error, integer or bulk string reply, nil for a null bulk string
+func (c *Cache) readReply() ([]byte, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	line = line[:len(line)-2]
+	switch line[0] {
+	case '+', ':':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, redisError(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		_, err = io.ReadFull(c.reader, data)
+		if err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
@@ -0,0 +1,108 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer answers AUTH, GET and SET commands from an in-memory map
+func fakeServer(t *testing.T, password string) (net.Listener, *sync.Map) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := &sync.Map{}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn, password, data)
+		}
+	}()
+	return l, data
+}
+
+func serve(conn net.Conn, password string, data *sync.Map) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authenticated := password == ""
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := make([]string, n)
+		for i := range args {
+			line, _ = r.ReadString('\n')
+			size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+			buf := make([]byte, size+2)
+			_, _ = io.ReadFull(r, buf)
+			args[i] = string(buf[:size])
+		}
+		switch {
+		case args[0] == "AUTH" && args[1] == password:
+			authenticated = true
+			_, _ = io.WriteString(conn, "+OK\r\n")
+		case !authenticated:
+			_, _ = io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+		case args[0] == "SET" && len(args) == 5 && args[3] == "PX":
+			data.Store(args[1], args[2])
+			_, _ = io.WriteString(conn, "+OK\r\n")
+		case args[0] == "GET":
+			value, ok := data.Load(args[1])
+			if !ok {
+				_, _ = io.WriteString(conn, "$-1\r\n")
+				continue
+			}
+			_, _ = fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(value.(string)), value)
+		default:
+			_, _ = io.WriteString(conn, "-ERR unknown command\r\n")
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	l, data := fakeServer(t, "secret")
+	defer l.Close()
+	ctx := context.Background()
+
+	c := New(l.Addr().String())
+	defer c.Close()
+	if _, err := c.Get(ctx, "key"); err == nil || !strings.Contains(err.Error(), "NOAUTH") {
+		t.Errorf("Expect NOAUTH error without password, got %v", err)
+	}
+
+	c.Password = "secret"
+	_ = c.Close()
+	value, err := c.Get(ctx, "key")
+	if err != nil || value != nil {
+		t.Errorf("Expect nil for a missing key, got %q, %v", value, err)
+	}
+	if err = c.Set(ctx, "key", []byte("{\"keys\":[]}\r\n"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	value, err = c.Get(ctx, "key")
+	if err != nil || string(value) != "{\"keys\":[]}\r\n" {
+		t.Errorf("Expect the stored value, got %q, %v", value, err)
+	}
+	if _, ok := data.Load("key"); !ok {
+		t.Error("Expect the value stored by the server")
+	}
+
+	l.Close()
+	_ = c.Close()
+	if _, err = c.Get(ctx, "key"); err == nil {
+		t.Error("Expect an error when the server is down")
+	}
+}
@@ -0,0 +1,43 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCachedKeySource(t *testing.T) {
+	certs, _ := getTestCerts()
+	certs.Expiry = time.Now().Add(time.Hour)
+	var fetches int
+	source := KeySourceFunc(func(context.Context) (*Certs, error) {
+		fetches++
+		return certs, nil
+	})
+	cache := NewMemoryCertCache()
+	ctx := context.Background()
+
+	// two instances sharing the cache
+	for i := 0; i < 2; i++ {
+		cached, err := CachedKeySource(cache, "certs", source).Keys(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cached.HasKey("3f3ef9c7803cd0b8d75247ee0d31fdd5c2cf3812") || !cached.Expiry.Equal(certs.Expiry) {
+			t.Errorf("Unexpected cached certs %v expiring %v", cached.KeyIDs(), cached.Expiry)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("Expect a single fetch, got %d", fetches)
+	}
+
+	_ = cache.Set(ctx, "certs", []byte("garbage"), time.Hour)
+	if _, err := CachedKeySource(cache, "certs", source).Keys(ctx); err != nil || fetches != 2 {
+		t.Errorf("Expect a corrupted entry to be refetched, got %v after %d fetches", err, fetches)
+	}
+
+	_ = cache.Set(ctx, "expired", []byte("x"), -time.Second)
+	if value, _ := cache.Get(ctx, "expired"); value != nil {
+		t.Errorf("Expect no value for an expired entry, got %q", value)
+	}
+}
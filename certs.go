@@ -1,71 +1,257 @@
 package googleIDVerifier
 
 import (
+	"context"
+	"crypto/ecdsa"
 	"crypto/rsa"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
 	"math/big"
 	"net/http"
 	"regexp"
+	"sort"
 	"strconv"
 	"time"
 )
 
 type Certs struct {
-	Keys   map[string]*rsa.PublicKey
+	Keys map[string]*rsa.PublicKey
+	// ECKeys are the P-256 keys verifying ES256 tokens
+	ECKeys map[string]*ecdsa.PublicKey
 	Expiry time.Time
 }
 
+// HasKey reports whether the certs hold a key of any type with the given kid
+func (c *Certs) HasKey(kid string) bool {
+	return c != nil && (c.Keys[kid] != nil || c.ECKeys[kid] != nil)
+}
+
+// KeyIDs returns the sorted kids of all the keys
+func (c *Certs) KeyIDs() []string {
+	if c == nil {
+		return nil
+	}
+	kids := make([]string, 0, len(c.Keys)+len(c.ECKeys))
+	for kid := range c.Keys {
+		kids = append(kids, kid)
+	}
+	for kid := range c.ECKeys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+	return kids
+}
+
 var (
-	cachedCerts *Certs
+	googleKeySet = NewGoogleKeySet()
 
 	// Google Sign on certificates.
 	googleOAuth2FederatedSignOnCertsURL = "https://www.googleapis.com/oauth2/v3/certs"
 )
 
+// defaultHTTPClient fetches keys when no client is configured, so a hanging endpoint cannot block verifications forever
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// defaultCacheAge is used when the certs response has no max-age, two hours
+const defaultCacheAge = 7200
+
 type key struct {
 	Kty string `json:"kty"`
 	Alg string `json:"alg"`
 	Use string `json:"use"`
-	Kid string `json:"Kid"`
-	N   string `json:"n"`
-	E   string `json:"e"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
 }
 
 type response struct {
 	Keys []*key `json:"keys"`
 }
 
-func getFederatedSignOnCerts() (*Certs, error) {
-	if cachedCerts != nil {
-		if time.Now().Before(cachedCerts.Expiry) {
-			return cachedCerts, nil
-		}
+func getFederatedSignOnCerts(ctx context.Context) (*Certs, error) {
+	return googleKeySet.current(ctx)
+}
+
+// ParseJWKS builds Certs from a JWKS document, such as the one served at
+// https://www.googleapis.com/oauth2/v3/certs. The certs expire after two hours. Keys of other types
+// or curves, e.g. of other identity providers, and invalid keys are skipped, returning ErrInvalidCert
+// only when none of the keys can verify tokens.
+func ParseJWKS(data []byte) (*Certs, error) {
+	res := &response{}
+	err := json.Unmarshal(data, res)
+	if err != nil {
+		return nil, err
 	}
+	return parseCerts(res, defaultCacheAge)
+}
 
-	res, cacheAge, err := fetchFederatedSignOnCerts()
+// ParseCerts builds Certs from either a JWKS document or x509 PEM certificates keyed by kid,
+// e.g. pinned keys shipped with a deployment which cannot reach Google
+func ParseCerts(data []byte) (*Certs, error) {
+	doc := map[string]json.RawMessage{}
+	err := json.Unmarshal(data, &doc)
 	if err != nil {
 		return nil, err
 	}
+	if _, ok := doc["keys"]; ok {
+		return ParseJWKS(data)
+	}
+	return ParsePEMCerts(data)
+}
 
-	parsedCerts, err := parseCerts(res, cacheAge)
+// ParsePEMCerts builds Certs from x509 PEM certificates keyed by kid, in the format served at
+// https://www.googleapis.com/oauth2/v1/certs. The certs expire after two hours. Like with ParseJWKS,
+// the certs whose key cannot verify tokens are skipped, returning ErrInvalidCert only when none can.
+func ParsePEMCerts(data []byte) (*Certs, error) {
+	pemCerts := map[string]string{}
+	err := json.Unmarshal(data, &pemCerts)
 	if err != nil {
 		return nil, err
 	}
+	keys := map[string]*rsa.PublicKey{}
+	ecKeys := map[string]*ecdsa.PublicKey{}
+	var lastErr error
+	for kid, pemCert := range pemCerts {
+		block, _ := pem.Decode([]byte(pemCert))
+		if block == nil || block.Type != "CERTIFICATE" {
+			lastErr = fmt.Errorf("%w: no PEM certificate for kid %s", ErrInvalidCert, kid)
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			lastErr = fmt.Errorf("%w: kid %s: %v", ErrInvalidCert, kid, err)
+			continue
+		}
+		switch key := cert.PublicKey.(type) {
+		case *rsa.PublicKey:
+			keys[kid] = key
+		case *ecdsa.PublicKey:
+			err = checkP256Key(kid, key)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			ecKeys[kid] = key
+		default:
+			lastErr = fmt.Errorf("%w: no RSA or ECDSA public key for kid %s", ErrInvalidCert, kid)
+		}
+	}
+	if len(pemCerts) > 0 && len(keys)+len(ecKeys) == 0 {
+		return nil, lastErr
+	}
+	return &Certs{
+		Keys:   keys,
+		ECKeys: ecKeys,
+		Expiry: time.Now().Add(time.Second * defaultCacheAge),
+	}, nil
+}
+
+// MergeCerts returns the union of both key sets, expiring with the first of them. A nil set is empty.
+// A kid present in both with different keys, or with an RSA key in one and an EC key in the other,
+// is a conflict and returns ErrKeyConflict.
+func MergeCerts(a, b *Certs) (*Certs, error) {
+	if a == nil {
+		a, b = b, a
+	}
+	if a == nil {
+		return &Certs{Keys: map[string]*rsa.PublicKey{}, ECKeys: map[string]*ecdsa.PublicKey{}}, nil
+	}
+	if b == nil {
+		b = &Certs{Expiry: a.Expiry}
+	}
+	keys := make(map[string]*rsa.PublicKey, len(a.Keys)+len(b.Keys))
+	for kid, key := range a.Keys {
+		keys[kid] = key
+	}
+	for kid, key := range b.Keys {
+		if existing, ok := keys[kid]; ok && (existing.E != key.E || existing.N.Cmp(key.N) != 0) {
+			return nil, fmt.Errorf("%w: %s", ErrKeyConflict, kid)
+		}
+		keys[kid] = key
+	}
+	ecKeys := make(map[string]*ecdsa.PublicKey, len(a.ECKeys)+len(b.ECKeys))
+	for kid, key := range a.ECKeys {
+		ecKeys[kid] = key
+	}
+	for kid, key := range b.ECKeys {
+		if existing, ok := ecKeys[kid]; ok && (existing.X.Cmp(key.X) != 0 || existing.Y.Cmp(key.Y) != 0) {
+			return nil, fmt.Errorf("%w: %s", ErrKeyConflict, kid)
+		}
+		ecKeys[kid] = key
+	}
+	for kid := range keys {
+		if _, ok := ecKeys[kid]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrKeyConflict, kid)
+		}
+	}
+	expiry := a.Expiry
+	if b.Expiry.Before(expiry) {
+		expiry = b.Expiry
+	}
+	return &Certs{Keys: keys, ECKeys: ecKeys, Expiry: expiry}, nil
+}
 
-	// cache certs
-	cachedCerts = parsedCerts
+func fetchGoogleCertsContext(ctx context.Context) (*Certs, error) {
+	return fetchCerts(ctx, nil, googleOAuth2FederatedSignOnCertsURL)
+}
 
-	return parsedCerts, nil
+// fetchCerts fetches keys in JWKS or PEM certs format, expiring when the response may no longer be cached.
+// client may be nil for defaultHTTPClient.
+func fetchCerts(ctx context.Context, client *http.Client, url string) (*Certs, error) {
+	return fetchCertsWithAuth(ctx, client, nil, url)
 }
 
-func fetchFederatedSignOnCerts() (*response, int64, error) {
-	resp, err := http.Get(googleOAuth2FederatedSignOnCertsURL)
+// fetchCertsWithAuth is fetchCerts authenticating the request with auth, when not nil
+func fetchCertsWithAuth(ctx context.Context, client *http.Client, auth RequestAuth, url string) (*Certs, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if auth != nil {
+		err = auth(req)
+		if err != nil {
+			return nil, fmt.Errorf("authenticating %s: %w", url, err)
+		}
+	}
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	cacheAge, err := cacheMaxAge(resp.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
-	cacheControl := resp.Header.Get("cache-control")
-	cacheAge := int64(7200) // Set default cacheAge to 2 hours
+	certs, err := ParseCerts(data)
+	if err != nil {
+		return nil, err
+	}
+	certs.Expiry = time.Now().Add(time.Second * time.Duration(cacheAge))
+	return certs, nil
+}
+
+// cacheMaxAge returns how long a response may be cached in seconds, from its Cache-Control max-age
+// or else from its Expires header, like the official Google client libraries
+func cacheMaxAge(header http.Header) (int64, error) {
+	cacheControl := header.Get("cache-control")
 	if len(cacheControl) > 0 {
 		re := regexp.MustCompile("max-age=([0-9]*)")
 		match := re.FindAllStringSubmatch(cacheControl, -1)
@@ -74,43 +260,58 @@ func fetchFederatedSignOnCerts() (*response, int64, error) {
 				maxAge := match[0][1]
 				maxAgeInt, err := strconv.ParseInt(maxAge, 10, 64)
 				if err != nil {
-					return nil, 0, err
+					return 0, err
 				}
-				cacheAge = maxAgeInt
+				return maxAgeInt, nil
 			}
 		}
 	}
-
-	res := &response{}
-	err = json.NewDecoder(resp.Body).Decode(&res)
-	if err != nil {
-		return nil, 0, err
+	if expires, err := http.ParseTime(header.Get("expires")); err == nil {
+		now := time.Now()
+		if date, err := http.ParseTime(header.Get("date")); err == nil {
+			now = date
+		}
+		if age := int64(expires.Sub(now).Seconds()); age > 0 {
+			return age, nil
+		}
+		return 0, nil
 	}
-
-	return res, cacheAge, nil
+	return defaultCacheAge, nil
 }
 
 func parseCerts(res *response, cacheAge int64) (*Certs, error) {
-	keys := map[string]*rsa.PublicKey{}
-	for _, key := range res.Keys {
-		if key.Use == "sig" && key.Kty == "RSA" {
-			n, err := base64.RawURLEncoding.DecodeString(key.N)
-			if err != nil {
-				return nil, err
-			}
-			e, err := base64.RawURLEncoding.DecodeString(key.E)
-			if err != nil {
-				return nil, err
-			}
-			ei := big.NewInt(0).SetBytes(e).Int64()
-			keys[key.Kid] = &rsa.PublicKey{
-				N: big.NewInt(0).SetBytes(n),
-				E: int(ei),
-			}
-		}
+	keys := parseKeys(res)
+	ecKeys := parseECKeys(res)
+	if len(res.Keys) > 0 && len(keys)+len(ecKeys) == 0 {
+		return nil, fmt.Errorf("%w: none of the %d keys is a valid RSA or P-256 signing key", ErrInvalidCert, len(res.Keys))
 	}
 	return &Certs{
 		Keys:   keys,
+		ECKeys: ecKeys,
 		Expiry: time.Now().Add(time.Second * time.Duration(cacheAge)),
 	}, nil
 }
+
+// parseKeys returns the RSA signing keys, skipping the ones which do not decode
+func parseKeys(res *response) map[string]*rsa.PublicKey {
+	keys := map[string]*rsa.PublicKey{}
+	for _, key := range res.Keys {
+		if key.Use != "sig" || key.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil || len(n) == 0 {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil || len(e) == 0 {
+			continue
+		}
+		ei := big.NewInt(0).SetBytes(e).Int64()
+		keys[key.Kid] = &rsa.PublicKey{
+			N: big.NewInt(0).SetBytes(n),
+			E: int(ei),
+		}
+	}
+	return keys
+}
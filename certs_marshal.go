@@ -0,0 +1,67 @@
+package googleIDVerifier
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"time"
+)
+
+// certsSnapshot is the serialized form of Certs: the keys in JWKS format plus the expiry
+type certsSnapshot struct {
+	Keys   []*key    `json:"keys"`
+	Expiry time.Time `json:"expiry"`
+}
+
+// MarshalJSON serializes the key set, kids and expiry included, as a JWKS document with an extra expiry field
+func (c *Certs) MarshalJSON() ([]byte, error) {
+	kids := c.KeyIDs()
+	snapshot := certsSnapshot{Keys: make([]*key, 0, len(kids)), Expiry: c.Expiry}
+	for _, kid := range kids {
+		if pub := c.Keys[kid]; pub != nil {
+			snapshot.Keys = append(snapshot.Keys, encodeKey(kid, pub))
+		} else {
+			snapshot.Keys = append(snapshot.Keys, encodeECKey(kid, c.ECKeys[kid]))
+		}
+	}
+	return json.Marshal(snapshot)
+}
+
+// UnmarshalJSON loads a key set serialized by MarshalJSON
+func (c *Certs) UnmarshalJSON(data []byte) error {
+	snapshot := certsSnapshot{}
+	err := json.Unmarshal(data, &snapshot)
+	if err != nil {
+		return err
+	}
+	certs, err := parseCerts(&response{Keys: snapshot.Keys}, 0)
+	if err != nil {
+		return err
+	}
+	c.Keys = certs.Keys
+	c.ECKeys = certs.ECKeys
+	c.Expiry = snapshot.Expiry
+	return nil
+}
+
+// MarshalBinary is MarshalJSON, so Certs can be stored by encoders using encoding.BinaryMarshaler
+func (c *Certs) MarshalBinary() ([]byte, error) {
+	return c.MarshalJSON()
+}
+
+// UnmarshalBinary is UnmarshalJSON
+func (c *Certs) UnmarshalBinary(data []byte) error {
+	return c.UnmarshalJSON(data)
+}
+
+func encodeKey(kid string, pub *rsa.PublicKey) *key {
+	return &key{
+		Kty: "RSA",
+		Alg: "RS256",
+		Use: "sig",
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
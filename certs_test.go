@@ -1,10 +1,19 @@
 package googleIDVerifier
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -92,13 +101,26 @@ func equalsRSAKeys(a, b map[string]*rsa.PublicKey, id string) error {
 }
 
 func TestGetFederatedSignonCerts(t *testing.T) {
-	certs, err := getFederatedSignOnCerts()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=19702, must-revalidate, no-transform")
+		http.ServeFile(w, r, "google-keys.json")
+	}))
+	defer srv.Close()
+
+	defer func(url string) {
+		googleOAuth2FederatedSignOnCertsURL = url
+		googleKeySet = NewGoogleKeySet()
+	}(googleOAuth2FederatedSignOnCertsURL)
+	googleOAuth2FederatedSignOnCertsURL = srv.URL
+	googleKeySet = NewGoogleKeySet()
+
+	certs, err := getFederatedSignOnCerts(context.Background())
 	if err != nil {
 		t.Error(err)
 		return
 	}
 
-	cachedCerts, err := getFederatedSignOnCerts()
+	cachedCerts, err := getFederatedSignOnCerts(context.Background())
 	if err != nil {
 		t.Error(err)
 		return
@@ -108,3 +130,133 @@ func TestGetFederatedSignonCerts(t *testing.T) {
 		t.Error("expecting same instance for cached certs")
 	}
 }
+
+func TestMarshalCerts(t *testing.T) {
+	certs, _ := getTestCerts()
+	data, err := json.Marshal(certs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := &Certs{}
+	err = json.Unmarshal(data, loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := equalCerts(certs, loaded); err != nil {
+		t.Error(err)
+	}
+	if len(loaded.Keys) != len(certs.Keys) {
+		t.Errorf("Expect %d keys, got %d", len(certs.Keys), len(loaded.Keys))
+	}
+
+	data, _ = certs.MarshalBinary()
+	loaded = &Certs{}
+	if err := loaded.UnmarshalBinary(data); err != nil || equalCerts(certs, loaded) != nil {
+		t.Errorf("Expect binary round trip, got %v", err)
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	certs, err := ParseJWKS([]byte(`{"keys": [
+		{"kty": "RSA", "alg": "RS256", "use": "sig", "kid": "k1", "n": "xM3ZHCgrJLe8y0rBZUWHOS1pCpJ2PjM_gw0WI9D0rljoZ7zWQpEC5UwpWaJqqDKxokt-kKP9GYXILqEsZrQ86qXvRZDPrP39RUjMl3Yl0hE4PlTx3aXuSE8SYqy506yduKjHw3seQHBiqSkVdLXSXqsEKUUrtFEgUxwL5L0yU4N3uJcAWK-oka8RxQSFJEilX5UOH-Qmz4UEeIr7Ma8cdsjibUc6xC9SRJtblmAdDDA_-1aMAJuYH8tGYnpTftwKbaaD0btq0LIzrsFnLu2--jaBul4u0k0jukolnUP0XSqE6NEc0iHTCdbKHZN6LrKVZoUqncTAS7Qa6TbgN1-lHw", "e": "AQAB"},
+		{"kty": "RSA", "use": "enc", "kid": "k2", "n": "AQAB", "e": "AQAB"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs.Keys) != 1 || certs.Keys["k1"] == nil || certs.Keys["k1"].E != 65537 {
+		t.Errorf("Expect only the signing key, got %v", certs.Keys)
+	}
+
+	if _, err := ParseJWKS([]byte(`{"keys": [`)); err == nil {
+		t.Error("Expect error for truncated JWKS")
+	}
+}
+
+func TestParsePEMCerts(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, _ := json.Marshal(map[string]string{
+		"k1": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+	})
+
+	certs, err := ParseCerts(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if certs.Keys["k1"] == nil || certs.Keys["k1"].N.Cmp(key.N) != 0 {
+		t.Errorf("Expect k1 public key, got %v", certs.Keys)
+	}
+
+	_, err = ParsePEMCerts([]byte(`{"k1": "not a certificate"}`))
+	if !errors.Is(err, ErrInvalidCert) {
+		t.Errorf("Expect ErrInvalidCert, got %v", err)
+	}
+
+	// ES256 needs a P-256 key
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err = x509.CreateCertificate(rand.Reader, template, template, &p384.PublicKey, p384)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, _ = json.Marshal(map[string]string{
+		"k2": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+	})
+	_, err = ParsePEMCerts(bundle)
+	if !errors.Is(err, ErrInvalidCert) {
+		t.Errorf("Expect ErrInvalidCert for a P-384 key, got %v", err)
+	}
+
+	// unusable certs are skipped when others are usable
+	rsaDER, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	bundle, _ = json.Marshal(map[string]string{
+		"k1": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rsaDER})),
+		"k2": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+	})
+	certs, err = ParsePEMCerts(bundle)
+	if err != nil || certs.Keys["k1"] == nil || certs.ECKeys["k2"] != nil {
+		t.Errorf("Expect only k1, got %v %v", certs, err)
+	}
+}
+
+func TestParseJWKSSkipsUnusableKeys(t *testing.T) {
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unusable := []*key{
+		encodeECKey("p384", &p384.PublicKey),
+		{Kty: "OKP", Use: "sig", Kid: "ed25519", Crv: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},
+		{Kty: "RSA", Use: "enc", Kid: "enc", N: "AQAB", E: "AQAB"},
+		{Kty: "RSA", Use: "sig", Kid: "bad-n", N: "!", E: "AQAB"},
+		{Kty: "EC", Use: "sig", Kid: "off-curve", Crv: "P-256", X: "AQ", Y: "AQ"},
+	}
+	data, _ := json.Marshal(&response{Keys: append([]*key{encodeECKey("p256", &p256.PublicKey)}, unusable...)})
+	certs, err := ParseJWKS(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs.Keys) != 0 || len(certs.ECKeys) != 1 || certs.ECKeys["p256"] == nil {
+		t.Errorf("Expect only the P-256 key, got %v %v", certs.Keys, certs.ECKeys)
+	}
+
+	data, _ = json.Marshal(&response{Keys: unusable})
+	if _, err = ParseJWKS(data); !errors.Is(err, ErrInvalidCert) {
+		t.Errorf("Expect ErrInvalidCert without usable keys, got %v", err)
+	}
+}
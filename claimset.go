@@ -1,17 +1,120 @@
 package googleIDVerifier
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
 	"golang.org/x/oauth2/jws"
 )
 
 type ClaimSet struct {
 	jws.ClaimSet
-	Email         string `json:"email"`
-	EmailVerified bool   `json:"email_verified"`
-	Name          string `json:"name"`
-	Picture       string `json:"picture"`
-	GivenName     string `json:"given_name"`
-	FamilyName    string `json:"family_name"`
-	Locale        string `json:"locale"`
-	HostedDomain  string `json:"hd, omitempty"`
+	Email           string `json:"email"`
+	EmailVerified   bool   `json:"email_verified"`
+	Name            string `json:"name"`
+	Picture         string `json:"picture"`
+	GivenName       string `json:"given_name"`
+	FamilyName      string `json:"family_name"`
+	Locale          string `json:"locale"`
+	HostedDomain    string `json:"hd,omitempty"`
+	AuthorizedParty string `json:"azp,omitempty"`
+	Nonce           string `json:"nonce,omitempty"`
+	AccessTokenHash string `json:"at_hash,omitempty"`
+
+	// Audiences are all the audiences of the token, whose aud may be a string or an array.
+	// Aud is the first one.
+	Audiences []string `json:"-"`
+
+	// payload is the encoded claims segment of the token, decoded on demand by Raw
+	payload string
+}
+
+// Raw returns every claim of the token, including the ones without a field, or nil for
+// a ClaimSet not decoded from a token
+func (c *ClaimSet) Raw() map[string]interface{} {
+	if c.payload == "" {
+		return nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(c.payload)
+	if err != nil {
+		return nil
+	}
+	claims := map[string]interface{}{}
+	if json.Unmarshal(decoded, &claims) != nil {
+		return nil
+	}
+	return claims
+}
+
+// UnmarshalJSON decodes the claims, accepting an aud which is a string or an array of strings
+func (c *ClaimSet) UnmarshalJSON(data []byte) error {
+	// claims has the fields of ClaimSet without its methods, the outer Aud shadowing the embedded one
+	type claims ClaimSet
+	aux := struct {
+		*claims
+		Aud audienceClaim `json:"aud"`
+	}{claims: (*claims)(c)}
+	err := json.Unmarshal(data, &aux)
+	if err != nil {
+		return err
+	}
+	c.Audiences = aux.Aud
+	c.Aud = ""
+	if len(aux.Aud) > 0 {
+		c.Aud = aux.Aud[0]
+	}
+	return nil
+}
+
+// MarshalJSON encodes the claims, with an aud which is an array for tokens with several audiences
+func (c ClaimSet) MarshalJSON() ([]byte, error) {
+	type claims ClaimSet
+	if len(c.Audiences) <= 1 {
+		return json.Marshal(claims(c))
+	}
+	return json.Marshal(struct {
+		claims
+		Aud []string `json:"aud"`
+	}{claims: claims(c), Aud: c.Audiences})
+}
+
+// audienceClaim decodes an aud claim which is a string or an array of strings
+type audienceClaim []string
+
+func (a *audienceClaim) UnmarshalJSON(data []byte) error {
+	var aud string
+	if json.Unmarshal(data, &aud) == nil {
+		*a = audienceClaim{aud}
+		return nil
+	}
+	var auds []string
+	err := json.Unmarshal(data, &auds)
+	if err != nil {
+		return err
+	}
+	*a = auds
+	return nil
+}
+
+// audiences returns Audiences, or else Aud for claims not decoded from a token
+func (c *ClaimSet) audiences() []string {
+	if len(c.Audiences) > 0 {
+		return c.Audiences
+	}
+	return []string{c.Aud}
+}
+
+type claimSetKey struct{}
+
+// ContextWithClaims returns a copy of ctx carrying the verified claims, as done by the httpmiddleware
+// and grpcauth packages
+func ContextWithClaims(ctx context.Context, claimSet *ClaimSet) context.Context {
+	return context.WithValue(ctx, claimSetKey{}, claimSet)
+}
+
+// ClaimsFromContext returns the claims stored by ContextWithClaims, if any
+func ClaimsFromContext(ctx context.Context) (*ClaimSet, bool) {
+	claimSet, ok := ctx.Value(claimSetKey{}).(*ClaimSet)
+	return claimSet, ok
 }
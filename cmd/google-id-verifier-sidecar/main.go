@@ -0,0 +1,55 @@
+// Command google-id-verifier-sidecar serves Google ID token verification on a local Unix socket.
+//
+//	google-id-verifier-sidecar -socket /run/gidv.sock -aud xxxxxx-yyyyyyy.apps.googleusercontent.com
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+	"github.com/fafg/google-id-verifier/sidecar"
+)
+
+func main() {
+	socket := flag.String("socket", "/tmp/google-id-verifier.sock", "path of the Unix socket to listen on")
+	aud := flag.String("aud", "", "comma separated default audiences, used when a request has none")
+	maxInFlight := flag.Int("max-in-flight", 0, "maximum concurrent verifications, unlimited when 0")
+	queueTimeout := flag.Duration("queue-timeout", 100*time.Millisecond, "how long a verification waits for a slot before a 429")
+	flag.Parse()
+
+	v := &googleIDVerifier.CertsVerifier{}
+	if *aud != "" {
+		v.DefaultAudience = strings.Split(*aud, ",")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	log.Printf("listening on %s", *socket)
+	failures := googleIDVerifier.NewFailureLogger(nil)
+	flushed := make(chan struct{})
+	go func() {
+		failures.Run(ctx)
+		close(flushed)
+	}()
+	h := sidecar.NewHandler(v, sidecar.WithFailureLogger(failures), sidecar.WithMaxInFlight(*maxInFlight, *queueTimeout))
+	err := sidecar.ListenAndServe(ctx, *socket, h)
+	// the suppressed failures are flushed once ctx is done, also when serving failed
+	cancel()
+	<-flushed
+	if err != nil {
+		log.Fatal(err)
+	}
+}
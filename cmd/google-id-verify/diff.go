@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+)
+
+type claimDiff struct {
+	Section string      `json:"section"`
+	Name    string      `json:"name"`
+	A       interface{} `json:"a"`
+	B       interface{} `json:"b"`
+}
+
+type comparison struct {
+	Diffs []claimDiff `json:"diffs"`
+	// A and B are "valid" or the verification error of each token, when --aud is set
+	A string `json:"a,omitempty"`
+	B string `json:"b,omitempty"`
+}
+
+// diff prints the differing header fields and claims of two tokens as JSON, and with --aud whether each
+// of them verifies
+func diff(args []string) {
+	flags := flag.NewFlagSet(os.Args[0]+" diff", flag.ExitOnError)
+	aud := flags.String("aud", "", "comma separated accepted audiences, also verifying both tokens when set")
+	iss := flags.String("iss", "", "comma separated accepted issuers, the Google ones when empty")
+	certsFile := flags.String("certs-file", "", "verify with the keys of this JWKS or PEM certs file instead of fetching the Google certs")
+	timeout := flags.Duration("timeout", 10*time.Second, "timeout fetching the Google certs")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s diff [flags] token-a token-b\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	a, b := flags.Arg(0), flags.Arg(1)
+
+	diffs, err := googleIDVerifier.DiffTokens(a, b)
+	if err != nil {
+		fail(err)
+	}
+	out := &comparison{Diffs: []claimDiff{}}
+	for _, d := range diffs {
+		out.Diffs = append(out.Diffs, claimDiff{Section: d.Section, Name: d.Name, A: d.A, B: d.B})
+	}
+
+	if *aud != "" {
+		issuers := googleIDVerifier.Issuers
+		if *iss != "" {
+			issuers = strings.Split(*iss, ",")
+		}
+		certs, err := loadCerts(*certsFile, *timeout)
+		if err != nil {
+			fail(err)
+		}
+		compared, err := googleIDVerifier.CompareTokens(a, b, certs, strings.Split(*aud, ","), issuers,
+			googleIDVerifier.MaxTokenLifetime)
+		if err != nil {
+			fail(err)
+		}
+		out.A, out.B = outcome(compared.ErrA), outcome(compared.ErrB)
+	}
+	data, _ := json.MarshalIndent(out, "", "  ")
+	fmt.Println(string(data))
+}
+
+// loadCerts reads the certs of path, or else fetches the Google certs
+func loadCerts(path string, timeout time.Duration) (*googleIDVerifier.Certs, error) {
+	if path != "" {
+		return googleIDVerifier.LoadCertsFile(path)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	keySet := googleIDVerifier.NewGoogleKeySet()
+	err := keySet.RefreshContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return keySet.Certs(), nil
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return "valid"
+}
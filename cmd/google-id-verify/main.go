@@ -0,0 +1,146 @@
+// Command google-id-verify verifies a Google ID token and prints its decoded header and claims as JSON,
+// exiting with code 1 when the token is not valid. The token is read from stdin when not given.
+//
+//	google-id-verify --aud=xxxxxx-yyyyyyy.apps.googleusercontent.com "$TOKEN"
+//	google-id-verify --insecure-decode < token.txt
+//
+// The diff subcommand compares the headers and claims of two tokens, and with --aud tells which of them
+// verify, e.g. to find out why a token works in staging but not in production:
+//
+//	google-id-verify diff --aud=xxxxxx-yyyyyyy.apps.googleusercontent.com "$STAGING_TOKEN" "$PROD_TOKEN"
+//
+// The monitor subcommand polls key endpoints and reports key rotations, alerting when keys disappear
+// unexpectedly or an endpoint serves invalid data:
+//
+//	google-id-verify monitor -interval 5m -webhook https://hooks.example.com/keys -snapshot keys.json
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+)
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "diff":
+			diff(os.Args[2:])
+			return
+		case "monitor":
+			monitor(os.Args[2:])
+			return
+		}
+	}
+	verify(os.Args[1:])
+}
+
+func verify(args []string) {
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	aud := flags.String("aud", "", "comma separated accepted audiences, usually OAuth2 client IDs")
+	iss := flags.String("iss", "", "comma separated accepted issuers, the Google ones when empty")
+	certsFile := flags.String("certs-file", "", "verify with the keys of this JWKS or PEM certs file instead of fetching the Google certs")
+	insecureDecode := flags.Bool("insecure-decode", false, "only decode the token, WITHOUT verifying it")
+	timeout := flags.Duration("timeout", 10*time.Second, "timeout fetching the Google certs")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s [flags] [token]\n       %s diff [flags] token-a token-b\n       %s monitor [flags]\n",
+			os.Args[0], os.Args[0], os.Args[0])
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	token, err := readToken(flags.Arg(0))
+	if err != nil {
+		fail(err)
+	}
+	decoded, err := decode(token)
+	if err != nil {
+		fail(err)
+	}
+	out, _ := json.MarshalIndent(decoded, "", "  ")
+	fmt.Println(string(out))
+	if *insecureDecode {
+		return
+	}
+
+	if *aud == "" {
+		fail(errors.New("--aud is required unless --insecure-decode is set"))
+	}
+	var opts []googleIDVerifier.Option
+	if *iss != "" {
+		opts = append(opts, googleIDVerifier.WithIssuers(strings.Split(*iss, ",")...))
+	}
+	v := googleIDVerifier.NewVerifier(opts...)
+	if *certsFile != "" {
+		certs, err := googleIDVerifier.LoadCertsFile(*certsFile)
+		if err != nil {
+			fail(err)
+		}
+		v = googleIDVerifier.NewStaticVerifier(certs, opts...)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	_, err = v.VerifyIDTokenContext(ctx, token, strings.Split(*aud, ",")...)
+	if err != nil {
+		fail(err)
+	}
+	fmt.Fprintln(os.Stderr, "valid token")
+}
+
+// readToken returns arg, or the content of stdin when arg is empty or "-"
+func readToken(arg string) (string, error) {
+	if arg != "" && arg != "-" {
+		return arg, nil
+	}
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", errors.New("no token given")
+	}
+	return token, nil
+}
+
+type decodedToken struct {
+	Header json.RawMessage `json:"header"`
+	Claims json.RawMessage `json:"claims"`
+}
+
+func decode(token string) (*decodedToken, error) {
+	s := strings.Split(token, ".")
+	if len(s) != 3 {
+		return nil, googleIDVerifier.ErrInvalidToken
+	}
+	decoded := &decodedToken{}
+	for i, name := range []string{"header", "claims"} {
+		segment, err := base64.RawURLEncoding.DecodeString(s[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", googleIDVerifier.ErrInvalidToken, name, err)
+		}
+		if !json.Valid(segment) {
+			return nil, fmt.Errorf("%w: %s is not JSON", googleIDVerifier.ErrInvalidToken, name)
+		}
+		if i == 0 {
+			decoded.Header = segment
+		} else {
+			decoded.Claims = segment
+		}
+	}
+	return decoded, nil
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "invalid token:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+)
+
+// monitor polls key endpoints and reports key rotations, alerting when keys disappear unexpectedly
+// or an endpoint serves invalid data
+func monitor(args []string) {
+	flags := flag.NewFlagSet(os.Args[0]+" monitor", flag.ExitOnError)
+	urls := flags.String("url", "https://www.googleapis.com/oauth2/v3/certs", "comma separated JWKS endpoints to monitor")
+	interval := flags.Duration("interval", 5*time.Minute, "polling interval")
+	minKeyAge := flags.Duration("min-key-age", 24*time.Hour, "keys removed sooner than this raise an alert")
+	webhook := flags.String("webhook", "", "URL receiving alert events as JSON")
+	snapshot := flags.String("snapshot", "", "file updated with the last keys fetched, in JWKS format")
+	once := flags.Bool("once", false, "poll once and exit with code 1 on alerts")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s monitor [flags]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	var alerted int32
+	var monitors []*googleIDVerifier.KeyMonitor
+	for _, url := range strings.Split(*urls, ",") {
+		url := url
+		m := googleIDVerifier.NewKeyMonitor(googleIDVerifier.URLKeySource(url, nil))
+		m.MinKeyAge = *minKeyAge
+		m.OnEvent = func(e googleIDVerifier.KeyEvent) {
+			log.Printf("%s %s %s %s", url, e.Kind, e.Kid, e.Error)
+			if e.Alert() {
+				atomic.StoreInt32(&alerted, 1)
+				notify(*webhook, url, e)
+			}
+			if *snapshot != "" && e.Kind != googleIDVerifier.KeyFetchFailed {
+				writeSnapshot(*snapshot, m.Certs())
+			}
+		}
+		monitors = append(monitors, m)
+	}
+
+	if *once {
+		for _, m := range monitors {
+			m.Poll()
+		}
+		if atomic.LoadInt32(&alerted) == 1 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+	for _, m := range monitors[1:] {
+		go m.Run(ctx, *interval)
+	}
+	_ = monitors[0].Run(ctx, *interval)
+}
+
+func notify(webhook string, url string, e googleIDVerifier.KeyEvent) {
+	if webhook == "" {
+		return
+	}
+	body, _ := json.Marshal(struct {
+		URL string `json:"url"`
+		googleIDVerifier.KeyEvent
+	}{url, e})
+	resp, err := http.Post(webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("webhook: %v", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+func writeSnapshot(path string, certs *googleIDVerifier.Certs) {
+	data, err := json.MarshalIndent(certs, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(path, data, 0644)
+	}
+	if err != nil {
+		log.Printf("snapshot: %v", err)
+	}
+}
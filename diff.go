@@ -0,0 +1,78 @@
+package googleIDVerifier
+
+import (
+	"reflect"
+	"sort"
+	"time"
+)
+
+// ClaimDiff is a header field or claim whose value differs between two tokens.
+// A is nil when the field is missing from the first token, B when missing from the second.
+type ClaimDiff struct {
+	// Section is either "header" or "claims"
+	Section string
+	Name    string
+	A       interface{}
+	B       interface{}
+}
+
+// TokenComparison is the result of comparing two tokens against the same verification config
+type TokenComparison struct {
+	Diffs []ClaimDiff
+	// ErrA and ErrB are the verification errors of each token, nil when it verifies
+	ErrA error
+	ErrB error
+}
+
+// DiffTokens decodes both tokens, without verifying them, and returns their differing
+// header fields and claims sorted by section and name
+func DiffTokens(a, b string) ([]ClaimDiff, error) {
+	var diffs []ClaimDiff
+	for i, section := range []string{"header", "claims"} {
+		segA, err := decodeRawSegment(a, i)
+		if err != nil {
+			return nil, err
+		}
+		segB, err := decodeRawSegment(b, i)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, diffSegment(section, segA, segB)...)
+	}
+	return diffs, nil
+}
+
+// CompareTokens diffs both tokens and verifies each of them against the given certs, audiences and issuers,
+// which helps explaining why one token verifies and the other one does not
+func CompareTokens(a, b string, certs *Certs, audiences []string, issuers []string, maxExpiry time.Duration) (*TokenComparison, error) {
+	diffs, err := DiffTokens(a, b)
+	if err != nil {
+		return nil, err
+	}
+	_, errA := VerifySignedJWTWithCerts(a, certs, audiences, issuers, maxExpiry)
+	_, errB := VerifySignedJWTWithCerts(b, certs, audiences, issuers, maxExpiry)
+	return &TokenComparison{Diffs: diffs, ErrA: errA, ErrB: errB}, nil
+}
+
+func diffSegment(section string, a, b map[string]interface{}) []ClaimDiff {
+	names := map[string]bool{}
+	for name := range a {
+		names[name] = true
+	}
+	for name := range b {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var diffs []ClaimDiff
+	for _, name := range sorted {
+		if !reflect.DeepEqual(a[name], b[name]) {
+			diffs = append(diffs, ClaimDiff{Section: section, Name: name, A: a[name], B: b[name]})
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,43 @@
+package googleIDVerifier
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestDiffTokens(t *testing.T) {
+	s := strings.Split(validTestToken, ".")
+	claims := `{"aud":"other","email":"plutonio@gmail.com","iss":"https://accounts.google.com"}`
+	other := s[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + "." + s[2]
+
+	diffs, err := DiffTokens(validTestToken, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]ClaimDiff{}
+	for _, d := range diffs {
+		if d.Section != "claims" {
+			t.Errorf("Unexpected %s diff on %s", d.Section, d.Name)
+		}
+		byName[d.Name] = d
+	}
+	if d, ok := byName["aud"]; !ok || d.B != "other" {
+		t.Errorf("Expect aud diff, got %+v", d)
+	}
+	if d, ok := byName["exp"]; !ok || d.B != nil {
+		t.Errorf("Expect exp missing in second token, got %+v", d)
+	}
+	if _, ok := byName["email"]; ok {
+		t.Error("Expect no diff for equal email")
+	}
+
+	certs, _ := getTestCerts()
+	cmp, err := CompareTokens(validTestToken, other, certs, []string{"other"}, Issuers, MaxTokenLifetime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmp.ErrB != ErrNoIssueTimeInToken {
+		t.Errorf("Expect ErrNoIssueTimeInToken for second token, got %v", cmp.ErrB)
+	}
+}
@@ -0,0 +1,180 @@
+package googleIDVerifier
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EntitlementSet is a set of entitlement values, e.g. role names
+type EntitlementSet map[string]struct{}
+
+// Has reports whether the set contains value
+func (s EntitlementSet) Has(value string) bool {
+	_, ok := s[value]
+	return ok
+}
+
+// HasAny reports whether the set contains at least one of values
+func (s EntitlementSet) HasAny(values ...string) bool {
+	for _, v := range values {
+		if s.Has(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAll reports whether the set contains all of values
+func (s EntitlementSet) HasAll(values ...string) bool {
+	for _, v := range values {
+		if !s.Has(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Entitlements are the entitlement sets of a token, by EntitlementClaim name
+type Entitlements map[string]EntitlementSet
+
+// Get returns the named set, empty if the token had none
+func (e Entitlements) Get(name string) EntitlementSet {
+	if s, ok := e[name]; ok {
+		return s
+	}
+	return EntitlementSet{}
+}
+
+// EntitlementClaim maps a custom claim to an entitlement set.
+// The claim can be a string, an array of strings, an object of booleans whose true keys are the values,
+// or a boolean, as in Firebase custom claims like {"admin": true}, which gives the set {Claim} when true.
+type EntitlementClaim struct {
+	// Name of the entitlement set, e.g. "roles"
+	Name string
+	// Claim is the, usually namespaced, claim name, e.g. "https://example.com/roles"
+	Claim string
+	// Allowed, if not empty, lists the only accepted values
+	Allowed []string
+	// Required rejects tokens without the claim
+	Required bool
+}
+
+// EntitlementExtractor extracts entitlements from verified tokens, caching them until the token expires
+type EntitlementExtractor struct {
+	Claims []EntitlementClaim
+
+	mu    sync.Mutex
+	cache map[string]cachedEntitlements
+}
+
+type cachedEntitlements struct {
+	entitlements Entitlements
+	exp          int64
+}
+
+// NewEntitlementExtractor returns an EntitlementExtractor for the given claims
+func NewEntitlementExtractor(claims ...EntitlementClaim) *EntitlementExtractor {
+	return &EntitlementExtractor{Claims: claims}
+}
+
+// Extract returns the entitlements of the token. It does not verify the token, so it should be
+// called after the standard checks.
+func (x *EntitlementExtractor) Extract(token string) (Entitlements, error) {
+	s := strings.Split(token, ".")
+	if len(s) != 3 {
+		return nil, ErrInvalidToken
+	}
+	cacheKey := s[1] + "." + s[2]
+	now := time.Now().Unix()
+
+	x.mu.Lock()
+	cached, ok := x.cache[cacheKey]
+	x.mu.Unlock()
+	if ok && cached.exp > now {
+		return cached.entitlements, nil
+	}
+
+	claims, err := decodeRawClaims(token)
+	if err != nil {
+		return nil, err
+	}
+	entitlements, err := x.extract(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	exp, _ := claims["exp"].(float64)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.cache == nil {
+		x.cache = map[string]cachedEntitlements{}
+	}
+	for k, c := range x.cache {
+		if c.exp <= now {
+			delete(x.cache, k)
+		}
+	}
+	x.cache[cacheKey] = cachedEntitlements{entitlements: entitlements, exp: int64(exp)}
+	return entitlements, nil
+}
+
+func (x *EntitlementExtractor) extract(claims map[string]interface{}) (Entitlements, error) {
+	entitlements := Entitlements{}
+	for _, c := range x.Claims {
+		value, ok := claims[c.Claim]
+		if !ok || value == nil {
+			if c.Required {
+				return nil, fmt.Errorf("%w: %s", ErrMissingClaim, c.Claim)
+			}
+			continue
+		}
+		set, err := entitlementSet(c.Claim, value)
+		if err != nil {
+			return nil, err
+		}
+		if len(c.Allowed) > 0 {
+			for v := range set {
+				if !contains(c.Allowed, v) {
+					return nil, fmt.Errorf("%w: %s in %s", ErrEntitlementNotAllowed, v, c.Claim)
+				}
+			}
+		}
+		entitlements[c.Name] = set
+	}
+	return entitlements, nil
+}
+
+func entitlementSet(claim string, value interface{}) (EntitlementSet, error) {
+	set := EntitlementSet{}
+	switch v := value.(type) {
+	case string:
+		set[v] = struct{}{}
+	case bool:
+		if v {
+			set[claim] = struct{}{}
+		}
+	case []interface{}:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s is not an array of strings", ErrWrongClaimType, claim)
+			}
+			set[s] = struct{}{}
+		}
+	case map[string]interface{}:
+		for k, item := range v {
+			b, ok := item.(bool)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s is not an object of booleans", ErrWrongClaimType, claim)
+			}
+			if b {
+				set[k] = struct{}{}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s is not an entitlement claim", ErrWrongClaimType, claim)
+	}
+	return set, nil
+}
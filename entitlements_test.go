@@ -0,0 +1,52 @@
+package googleIDVerifier
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func tokenWithClaims(claims string) string {
+	s := strings.Split(validTestToken, ".")
+	return s[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + "." + s[2]
+}
+
+func TestEntitlements(t *testing.T) {
+	x := NewEntitlementExtractor(
+		EntitlementClaim{Name: "roles", Claim: "https://example.com/roles", Allowed: []string{"reader", "writer"}},
+		EntitlementClaim{Name: "features", Claim: "https://example.com/features"},
+		EntitlementClaim{Name: "admin", Claim: "admin"},
+	)
+	token := tokenWithClaims(`{"exp": 4102444800, "https://example.com/roles": ["reader", "writer"],
+		"https://example.com/features": {"beta": true, "legacy": false}, "admin": true}`)
+
+	e, err := x.Extract(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.Get("roles").HasAll("reader", "writer") || !e.Get("features").Has("beta") || e.Get("features").Has("legacy") {
+		t.Errorf("Unexpected entitlements %v", e)
+	}
+	if !e.Get("admin").Has("admin") || e.Get("missing").HasAny("admin") {
+		t.Errorf("Unexpected entitlements %v", e)
+	}
+	if cached, _ := x.Extract(token); len(x.cache) != 1 || !cached.Get("roles").Has("reader") {
+		t.Errorf("Expect entitlements to be cached")
+	}
+
+	_, err = x.Extract(tokenWithClaims(`{"https://example.com/roles": "owner"}`))
+	if !errors.Is(err, ErrEntitlementNotAllowed) {
+		t.Errorf("Expect ErrEntitlementNotAllowed, got %v", err)
+	}
+	_, err = x.Extract(tokenWithClaims(`{"https://example.com/roles": [1]}`))
+	if !errors.Is(err, ErrWrongClaimType) {
+		t.Errorf("Expect ErrWrongClaimType, got %v", err)
+	}
+
+	x.Claims[0].Required = true
+	_, err = NewEntitlementExtractor(x.Claims...).Extract(tokenWithClaims(`{"admin": true}`))
+	if !errors.Is(err, ErrMissingClaim) {
+		t.Errorf("Expect ErrMissingClaim, got %v", err)
+	}
+}
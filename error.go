@@ -9,6 +9,8 @@ var (
 
 	ErrWrongSignature = errors.New("Wrong token signature")
 
+	ErrUnsupportedAlgorithm = errors.New("Unsupported token algorithm")
+
 	ErrNoIssueTimeInToken = errors.New("No issue time in token")
 
 	ErrNoExpirationTimeInToken = errors.New("No expiration time in token")
@@ -18,4 +20,43 @@ var (
 	ErrTokenUsedTooEarly = errors.New("Token used too early")
 
 	ErrTokenUsedTooLate = errors.New("Token used too late")
+
+	// ErrTokenExpired is ErrTokenUsedTooLate, returned along with the claims by a CertsVerifier with AllowExpired
+	ErrTokenExpired = ErrTokenUsedTooLate
+
+	ErrWrongIssuer = errors.New("wrong issuer")
+
+	ErrWrongAudience = errors.New("wrong aud")
+
+	ErrMissingClaim = errors.New("Missing claim")
+
+	ErrWrongClaimType = errors.New("Wrong claim type")
+
+	ErrNoKeySource = errors.New("No key source configured")
+
+	ErrUnknownTenant = errors.New("No tenant for token audience")
+
+	ErrInvalidCert = errors.New("Invalid certificate")
+
+	ErrKeyConflict = errors.New("Different keys for the same kid")
+
+	ErrHostedDomainNotAllowed = errors.New("Hosted domain not allowed")
+
+	ErrEmailNotVerified = errors.New("Email not verified")
+
+	ErrEmailNotAllowed = errors.New("Email not allowed")
+
+	ErrNoToken = errors.New("No token in request")
+
+	ErrEntitlementNotAllowed = errors.New("Entitlement not allowed")
+
+	ErrWrongNonce = errors.New("Wrong nonce")
+
+	ErrAuthorizedPartyNotAllowed = errors.New("Authorized party not allowed")
+
+	ErrInvalidAccessToken = errors.New("Invalid access token")
+
+	ErrWrongAccessTokenHash = errors.New("Access token does not match at_hash")
+
+	ErrClockSkew = errors.New("Clock off from the reference clock")
 )
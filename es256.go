@@ -0,0 +1,97 @@
+package googleIDVerifier
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	// rs256 is the JWS algorithm of tokens signed with an RSA key, such as the Google ID tokens
+	rs256 = "RS256"
+	// es256 is the JWS algorithm of tokens signed with a P-256 ECDSA key
+	es256 = "ES256"
+)
+
+// parseECKeys returns the P-256 signing keys, skipping the ones on other curves or which do not decode
+func parseECKeys(res *response) map[string]*ecdsa.PublicKey {
+	keys := map[string]*ecdsa.PublicKey{}
+	for _, key := range res.Keys {
+		if key.Use != "sig" || key.Kty != "EC" || key.Crv != "P-256" {
+			continue
+		}
+		x, err := base64.RawURLEncoding.DecodeString(key.X)
+		if err != nil {
+			continue
+		}
+		y, err := base64.RawURLEncoding.DecodeString(key.Y)
+		if err != nil {
+			continue
+		}
+		pub := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     big.NewInt(0).SetBytes(x),
+			Y:     big.NewInt(0).SetBytes(y),
+		}
+		if checkP256Key(key.Kid, pub) != nil {
+			continue
+		}
+		keys[key.Kid] = pub
+	}
+	return keys
+}
+
+// checkP256Key rejects the keys which cannot verify ES256 signatures: on another curve than P-256
+// or whose point is not on the curve
+func checkP256Key(kid string, pub *ecdsa.PublicKey) error {
+	if pub.Curve != elliptic.P256() {
+		return fmt.Errorf("%w: unsupported curve %s for kid %s", ErrInvalidCert, pub.Curve.Params().Name, kid)
+	}
+	if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return fmt.Errorf("%w: point not on curve for kid %s", ErrInvalidCert, kid)
+	}
+	return nil
+}
+
+func encodeECKey(kid string, pub *ecdsa.PublicKey) *key {
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	return &key{
+		Kty: "EC",
+		Alg: es256,
+		Use: "sig",
+		Kid: kid,
+		Crv: pub.Curve.Params().Name,
+		X:   base64.RawURLEncoding.EncodeToString(padded(pub.X.Bytes(), size)),
+		Y:   base64.RawURLEncoding.EncodeToString(padded(pub.Y.Bytes(), size)),
+	}
+}
+
+func padded(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	return append(make([]byte, size-len(b)), b...)
+}
+
+// checkES256Signature verifies a JWS signature made of the fixed size r and s of an ECDSA P-256 signature
+func checkES256Signature(token string, key *ecdsa.PublicKey) error {
+	i := strings.LastIndex(token, ".")
+	if i < 0 {
+		return ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(token[i+1:])
+	if err != nil || len(sig) != 64 {
+		return ErrWrongSignature
+	}
+	h := sha256.Sum256([]byte(token[:i]))
+	r := big.NewInt(0).SetBytes(sig[:32])
+	s := big.NewInt(0).SetBytes(sig[32:])
+	if !ecdsa.Verify(key, h[:], r, s) {
+		return ErrWrongSignature
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package googleIDVerifier
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func signES256(t *testing.T, priv *ecdsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	h := sha256.Sum256([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, priv, h[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := append(padded(r.Bytes(), 32), padded(s.Bytes(), 32)...)
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestES256(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks, _ := json.Marshal(response{Keys: []*key{encodeECKey("ec-kid", &priv.PublicKey)}})
+	certs, err := ParseJWKS(jwks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !certs.HasKey("ec-kid") {
+		t.Fatal("Expect EC key in certs")
+	}
+
+	now := time.Now()
+	token := signES256(t, priv, "ec-kid", map[string]interface{}{
+		"iss": "https://accounts.google.com", "aud": "client-id", "sub": "1",
+		"iat": now.Unix(), "exp": now.Add(time.Hour).Unix(),
+	})
+	_, err = VerifySignedJWTWithCerts(token, certs, []string{"client-id"}, Issuers, MaxTokenLifetime)
+	if !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("Expect ES256 to need opting in, got %v", err)
+	}
+	es256Only := WithAllowedAlgorithms(es256)
+	claimSet, err := VerifySignedJWTWithCerts(token, certs, []string{"client-id"}, Issuers, MaxTokenLifetime, es256Only)
+	if err != nil || claimSet.Sub != "1" {
+		t.Fatalf("Expect ES256 token to verify, got %v", err)
+	}
+
+	_, err = VerifySignedJWTWithCerts(token[:len(token)-4]+"AAAA", certs, []string{"client-id"}, Issuers, MaxTokenLifetime, es256Only)
+	if !errors.Is(err, ErrWrongSignature) {
+		t.Errorf("Expect ErrWrongSignature, got %v", err)
+	}
+
+	rsaCerts, _ := getTestCerts()
+	merged, err := MergeCerts(rsaCerts, certs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := merged.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := &Certs{}
+	if err = restored.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if len(restored.KeyIDs()) != len(merged.KeyIDs()) || restored.ECKeys["ec-kid"].X.Cmp(priv.X) != 0 {
+		t.Errorf("Expect EC key to survive a JSON round trip, got %v", restored.KeyIDs())
+	}
+	if _, err = VerifySignedJWTWithCerts(token, restored, []string{"client-id"}, Issuers, MaxTokenLifetime, es256Only); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,72 @@
+package googleIDVerifier
+
+import (
+	"net/http"
+	"strings"
+)
+
+// TokenExtractor extracts a token from request headers. It takes headers rather than an
+// *http.Request so gRPC metadata can be passed as well. It returns ErrNoToken when the
+// headers carry no token.
+type TokenExtractor interface {
+	ExtractToken(header http.Header) (string, error)
+}
+
+// TokenExtractorFunc adapts a function to TokenExtractor
+type TokenExtractorFunc func(header http.Header) (string, error)
+
+// ExtractToken calls f
+func (f TokenExtractorFunc) ExtractToken(header http.Header) (string, error) {
+	return f(header)
+}
+
+// BearerExtractor extracts the token of an "Authorization: Bearer <token>" header
+func BearerExtractor() TokenExtractor {
+	return TokenExtractorFunc(func(header http.Header) (string, error) {
+		auth := header.Get("Authorization")
+		if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+			return "", ErrNoToken
+		}
+		token := strings.TrimSpace(auth[7:])
+		if token == "" {
+			return "", ErrNoToken
+		}
+		return token, nil
+	})
+}
+
+// HeaderExtractor extracts the token carried as the whole value of the named header,
+// e.g. x-goog-iap-jwt-assertion
+func HeaderExtractor(name string) TokenExtractor {
+	return TokenExtractorFunc(func(header http.Header) (string, error) {
+		token := strings.TrimSpace(header.Get(name))
+		if token == "" {
+			return "", ErrNoToken
+		}
+		return token, nil
+	})
+}
+
+// CookieExtractor extracts the token stored in the named cookie
+func CookieExtractor(name string) TokenExtractor {
+	return TokenExtractorFunc(func(header http.Header) (string, error) {
+		cookie, err := (&http.Request{Header: header}).Cookie(name)
+		if err != nil || cookie.Value == "" {
+			return "", ErrNoToken
+		}
+		return cookie.Value, nil
+	})
+}
+
+// FirstTokenExtractor tries the given extractors in order and returns the first token found
+func FirstTokenExtractor(extractors ...TokenExtractor) TokenExtractor {
+	return TokenExtractorFunc(func(header http.Header) (string, error) {
+		for _, e := range extractors {
+			token, err := e.ExtractToken(header)
+			if err != ErrNoToken {
+				return token, err
+			}
+		}
+		return "", ErrNoToken
+	})
+}
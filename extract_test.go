@@ -0,0 +1,36 @@
+package googleIDVerifier
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestTokenExtractors(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "bearer  abc ")
+	header.Set("X-Goog-Iap-Jwt-Assertion", "iap")
+	header.Set("Cookie", "session=cookie; other=1")
+
+	for expected, e := range map[string]TokenExtractor{
+		"abc":    BearerExtractor(),
+		"iap":    HeaderExtractor("x-goog-iap-jwt-assertion"),
+		"cookie": CookieExtractor("session"),
+	} {
+		token, err := e.ExtractToken(header)
+		if err != nil || token != expected {
+			t.Errorf("Expect %s, got %q %v", expected, token, err)
+		}
+	}
+
+	first := FirstTokenExtractor(HeaderExtractor("x-missing"), CookieExtractor("session"), BearerExtractor())
+	if token, _ := first.ExtractToken(header); token != "cookie" {
+		t.Errorf("Expect first found token, got %s", token)
+	}
+	if _, err := first.ExtractToken(http.Header{}); err != ErrNoToken {
+		t.Errorf("Expect ErrNoToken, got %v", err)
+	}
+	header.Set("Authorization", "Basic abc")
+	if _, err := BearerExtractor().ExtractToken(header); err != ErrNoToken {
+		t.Errorf("Expect ErrNoToken for basic auth, got %v", err)
+	}
+}
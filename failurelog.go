@@ -0,0 +1,145 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var failureCodes = []error{
+	ErrInvalidToken, ErrPublicKeyNotFound, ErrWrongSignature, ErrUnsupportedAlgorithm, ErrNoIssueTimeInToken, ErrNoExpirationTimeInToken,
+	ErrExpirationTimeTooFarInFuture, ErrTokenUsedTooEarly, ErrTokenUsedTooLate, ErrWrongIssuer, ErrWrongAudience,
+	ErrMissingClaim, ErrWrongClaimType, ErrHostedDomainNotAllowed, ErrEmailNotVerified, ErrEmailNotAllowed,
+	ErrEntitlementNotAllowed, ErrUnknownTenant, ErrNoKeySource, ErrWrongNonce, ErrAuthorizedPartyNotAllowed,
+	ErrInvalidAccessToken, ErrWrongAccessTokenHash, ErrNoToken,
+}
+
+// FailureLogger logs verification failures with at most Burst lines per error code and Interval,
+// so an attack or a broken client cannot flood the logs. Every failure is still counted, and the
+// suppressed ones are summed up with the next failure of their code, or by Flush.
+type FailureLogger struct {
+	Logf     func(format string, args ...interface{})
+	Interval time.Duration
+	Burst    int
+	// Metrics, if set, counts every failure per error code, see MetricFailureLogs. It is usually the
+	// Metrics of the verifier, so the aggregate counts are exported along with its other telemetry.
+	Metrics Metrics
+
+	mu      sync.Mutex
+	windows map[string]*failureWindow
+	totals  map[string]uint64
+}
+
+type failureWindow struct {
+	start      time.Time
+	logged     int
+	suppressed int
+}
+
+// NewFailureLogger returns a FailureLogger logging through logf, or the standard logger when nil,
+// at most 10 lines per error code and minute
+func NewFailureLogger(logf func(format string, args ...interface{})) *FailureLogger {
+	if logf == nil {
+		logf = log.Printf
+	}
+	return &FailureLogger{Logf: logf, Interval: time.Minute, Burst: 10}
+}
+
+// Log records a verification failure, logging it unless its error code is over the limit
+func (l *FailureLogger) Log(err error) {
+	if err == nil {
+		return
+	}
+	code := failureCode(err)
+	now := time.Now()
+
+	l.mu.Lock()
+	if l.windows == nil {
+		l.windows = map[string]*failureWindow{}
+		l.totals = map[string]uint64{}
+	}
+	l.totals[code]++
+	w, ok := l.windows[code]
+	if !ok {
+		w = &failureWindow{start: now}
+		l.windows[code] = w
+	}
+	suppressed := 0
+	if now.Sub(w.start) >= l.Interval {
+		suppressed = w.suppressed
+		*w = failureWindow{start: now}
+	}
+	logged := w.logged < l.Burst
+	if logged {
+		w.logged++
+	} else {
+		w.suppressed++
+	}
+	l.mu.Unlock()
+
+	if l.Metrics != nil {
+		l.Metrics.IncCounter(MetricFailureLogs, map[string]string{"code": code, "suppressed": strconv.FormatBool(!logged)})
+	}
+	if suppressed > 0 {
+		l.Logf("googleIDVerifier: %d %q verification failures suppressed", suppressed, code)
+	}
+	if logged {
+		l.Logf("googleIDVerifier: verification failed: %v", err)
+	}
+}
+
+// Flush logs the failures suppressed so far, which are otherwise only reported along with the next
+// failure of their code after Interval, never for the last burst of an attack
+func (l *FailureLogger) Flush() {
+	l.mu.Lock()
+	suppressed := map[string]int{}
+	for code, w := range l.windows {
+		if w.suppressed > 0 {
+			suppressed[code] = w.suppressed
+			w.suppressed = 0
+		}
+	}
+	l.mu.Unlock()
+
+	for code, n := range suppressed {
+		l.Logf("googleIDVerifier: %d %q verification failures suppressed", n, code)
+	}
+}
+
+// Run flushes the suppressed failures every Interval until ctx is done, and a last time then
+func (l *FailureLogger) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			l.Flush()
+			return
+		case <-ticker.C:
+			l.Flush()
+		}
+	}
+}
+
+// Counts returns the number of failures seen per error code, including suppressed ones
+func (l *FailureLogger) Counts() map[string]uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	counts := make(map[string]uint64, len(l.totals))
+	for code, n := range l.totals {
+		counts[code] = n
+	}
+	return counts
+}
+
+func failureCode(err error) string {
+	for _, code := range failureCodes {
+		if errors.Is(err, code) {
+			return code.Error()
+		}
+	}
+	return "other"
+}
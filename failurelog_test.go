@@ -0,0 +1,95 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFailureLogger(t *testing.T) {
+	var lines []string
+	l := NewFailureLogger(func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	})
+	l.Burst = 2
+
+	for i := 0; i < 5; i++ {
+		l.Log(ErrWrongSignature)
+	}
+	l.Log(fmt.Errorf("%w: x", ErrWrongAudience))
+	if len(lines) != 3 {
+		t.Errorf("Expect 2 signature and 1 audience lines, got %v", lines)
+	}
+
+	counts := l.Counts()
+	if counts[ErrWrongSignature.Error()] != 5 || counts[ErrWrongAudience.Error()] != 1 {
+		t.Errorf("Unexpected counts %v", counts)
+	}
+
+	l.Interval = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	l.Log(ErrWrongSignature)
+	if len(lines) != 5 {
+		t.Errorf("Expect suppressed summary and a new line, got %v", lines)
+	}
+}
+
+func TestFailureLoggerFlush(t *testing.T) {
+	var mu sync.Mutex
+	var lines []string
+	l := NewFailureLogger(func(format string, args ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, fmt.Sprintf(format, args...))
+	})
+	l.Burst = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		l.Run(ctx)
+		close(done)
+	}()
+	for i := 0; i < 4; i++ {
+		l.Log(ErrWrongSignature)
+	}
+	// no failure follows the burst: the suppressed ones are reported when the logger stops
+	cancel()
+	<-done
+	if len(lines) != 2 || lines[1] != `googleIDVerifier: 3 "Wrong token signature" verification failures suppressed` {
+		t.Errorf("Expect the suppressed failures to be flushed, got %v", lines)
+	}
+	l.Flush()
+	if len(lines) != 2 {
+		t.Errorf("Expect suppressed failures to be reported once, got %v", lines)
+	}
+}
+
+type failureMetrics map[string]int
+
+func (m failureMetrics) IncCounter(name string, labels map[string]string) {
+	m[name+" "+labels["code"]+" "+labels["suppressed"]]++
+}
+
+func (m failureMetrics) ObserveHistogram(string, float64, map[string]string) {}
+
+func (m failureMetrics) SetGauge(string, float64, map[string]string) {}
+
+func TestFailureLoggerMetrics(t *testing.T) {
+	m := failureMetrics{}
+	l := NewFailureLogger(func(string, ...interface{}) {})
+	l.Burst = 1
+	l.Metrics = m
+
+	for i := 0; i < 3; i++ {
+		l.Log(ErrWrongSignature)
+	}
+	l.Log(ErrNoToken)
+	code := ErrWrongSignature.Error()
+	if m[MetricFailureLogs+" "+code+" false"] != 1 || m[MetricFailureLogs+" "+code+" true"] != 2 ||
+		m[MetricFailureLogs+" "+ErrNoToken.Error()+" false"] != 1 {
+		t.Errorf("Expect every failure to be counted per code, got %v", m)
+	}
+}
@@ -0,0 +1,169 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+var (
+	// Certs signing the Firebase ID tokens
+	firebaseIDTokenCertsURL = "https://www.googleapis.com/robot/v1/metadata/x509/securetoken@system.gserviceaccount.com"
+
+	// Certs signing the Firebase session cookies
+	firebaseSessionCookieCertsURL = "https://www.googleapis.com/identitytoolkit/v3/relyingparty/publicKeys"
+)
+
+const (
+	firebaseIDTokenIssuer       = "https://securetoken.google.com/"
+	firebaseSessionCookieIssuer = "https://session.firebase.google.com/"
+
+	// firebaseSessionCookieLifetime is the longest session Firebase lets create, two weeks
+	firebaseSessionCookieLifetime = 14 * 24 * time.Hour
+
+	// firebaseMaxSubjectLength is the longest uid Firebase accepts
+	firebaseMaxSubjectLength = 128
+)
+
+// FirebaseToken is a verified Firebase ID token or session cookie
+type FirebaseToken struct {
+	*ClaimSet
+
+	// AuthTime is when the user signed in, which refreshing the token does not change
+	AuthTime time.Time
+	// SignInProvider is how the user signed in, e.g. "password", "google.com" or "custom"
+	SignInProvider string
+	// Tenant is the Identity Platform tenant of the user, if any
+	Tenant string
+	// Identities are the identifiers of the user by provider, e.g. "email" or "google.com"
+	Identities map[string][]string
+}
+
+type firebaseClaims struct {
+	AuthTime int64 `json:"auth_time"`
+	Firebase struct {
+		SignInProvider string              `json:"sign_in_provider"`
+		Tenant         string              `json:"tenant"`
+		Identities     map[string][]string `json:"identities"`
+	} `json:"firebase"`
+}
+
+// FirebaseVerifier verifies the ID tokens and session cookies issued by Firebase Authentication
+// for a project, without the Admin SDK
+//
+//	v := googleIDVerifier.NewFirebaseVerifier("my-project")
+//	token, err := v.VerifyIDToken(ctx, idToken)
+type FirebaseVerifier struct {
+	ProjectID string
+
+	idTokens       *CertsVerifier
+	sessionCookies *CertsVerifier
+}
+
+// NewFirebaseVerifier returns a FirebaseVerifier for the tokens of the Firebase project projectID.
+// The options apply to both ID tokens and session cookies, e.g. WithHTTPClient or WithMetrics.
+func NewFirebaseVerifier(projectID string, opts ...Option) *FirebaseVerifier {
+	return &FirebaseVerifier{
+		ProjectID:      projectID,
+		idTokens:       newFirebaseCertsVerifier(projectID, firebaseIDTokenIssuer, firebaseIDTokenCertsURL, 0, opts),
+		sessionCookies: newFirebaseCertsVerifier(projectID, firebaseSessionCookieIssuer, firebaseSessionCookieCertsURL, firebaseSessionCookieLifetime, opts),
+	}
+}
+
+func newFirebaseCertsVerifier(projectID string, issuer string, certsURL string, maxLifetime time.Duration,
+	opts []Option) *CertsVerifier {
+	v := &CertsVerifier{}
+	defaults := []Option{
+		WithIssuers(issuer + projectID),
+		WithAudiences(projectID),
+		WithMaxTokenLifetime(maxLifetime),
+		WithAllowedAlgorithms(rs256, es256),
+		WithClaimValidator(func(claimSet *ClaimSet) error {
+			return checkFirebaseClaims(claimSet, v.now(), v.clockSkew())
+		}),
+	}
+	for _, opt := range append(defaults, opts...) {
+		opt(v)
+	}
+	if v.KeySet == nil {
+		v.KeySet = NewKeySetContext(nil, func(ctx context.Context) (*Certs, error) {
+			return fetchCerts(ctx, v.HTTPClient, certsURL)
+		})
+	}
+	return v
+}
+
+// VerifyIDToken checks a Firebase ID token, as sent by the client SDKs after a sign-in
+func (v *FirebaseVerifier) VerifyIDToken(ctx context.Context, idToken string) (*FirebaseToken, error) {
+	return verifyFirebaseToken(ctx, v.idTokens, idToken)
+}
+
+// VerifySessionCookie checks a Firebase session cookie, as created by the Admin SDK from an ID token
+func (v *FirebaseVerifier) VerifySessionCookie(ctx context.Context, cookie string) (*FirebaseToken, error) {
+	return verifyFirebaseToken(ctx, v.sessionCookies, cookie)
+}
+
+// StartKeyRefresh keeps the keys of both ID tokens and session cookies fresh in the background, see KeySet.StartRefresh
+func (v *FirebaseVerifier) StartKeyRefresh(ctx context.Context) error {
+	err := v.idTokens.StartKeyRefresh(ctx)
+	if err != nil {
+		return err
+	}
+	return v.sessionCookies.StartKeyRefresh(ctx)
+}
+
+func verifyFirebaseToken(ctx context.Context, v *CertsVerifier, token string) (*FirebaseToken, error) {
+	claimSet, err := v.VerifyIDTokenContext(ctx, token)
+	if claimSet == nil {
+		return nil, err
+	}
+	claims, decodeErr := decodeFirebaseClaims(claimSet)
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	// err is ErrTokenExpired with WithAllowExpired
+	return &FirebaseToken{
+		ClaimSet:       claimSet,
+		AuthTime:       time.Unix(claims.AuthTime, 0),
+		SignInProvider: claims.Firebase.SignInProvider,
+		Tenant:         claims.Firebase.Tenant,
+		Identities:     claims.Firebase.Identities,
+	}, err
+}
+
+// checkFirebaseClaims checks the claims Firebase adds to the standard ones: a sign-in time
+// not in the future and a non-empty uid of at most 128 characters
+func checkFirebaseClaims(claimSet *ClaimSet, now time.Time, clockSkew time.Duration) error {
+	claims, err := decodeFirebaseClaims(claimSet)
+	if err != nil {
+		return err
+	}
+	if claims.AuthTime == 0 {
+		return fmt.Errorf("%w: auth_time", ErrMissingClaim)
+	}
+	if now.Add(clockSkew).Before(time.Unix(claims.AuthTime, 0)) {
+		return fmt.Errorf("%w: auth_time in the future", ErrTokenUsedTooEarly)
+	}
+	if claimSet.Sub == "" {
+		return fmt.Errorf("%w: sub", ErrMissingClaim)
+	}
+	if len(claimSet.Sub) > firebaseMaxSubjectLength {
+		return fmt.Errorf("%w: sub longer than %d characters", ErrInvalidToken, firebaseMaxSubjectLength)
+	}
+	return nil
+}
+
+func decodeFirebaseClaims(claimSet *ClaimSet) (*firebaseClaims, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(claimSet.payload)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	claims := &firebaseClaims{}
+	err = json.Unmarshal(decoded, claims)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWrongClaimType, err)
+	}
+	return claims, nil
+}
@@ -0,0 +1,84 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFirebaseVerifier(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(response{Keys: []*key{encodeECKey("fb", &priv.PublicKey)}})
+	}))
+	defer srv.Close()
+	defer func(url string) { firebaseIDTokenCertsURL = url }(firebaseIDTokenCertsURL)
+	defer func(url string) { firebaseSessionCookieCertsURL = url }(firebaseSessionCookieCertsURL)
+	firebaseIDTokenCertsURL = srv.URL
+	firebaseSessionCookieCertsURL = srv.URL
+
+	v := NewFirebaseVerifier("my-project")
+	ctx := context.Background()
+	now := time.Now()
+	claims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss": "https://securetoken.google.com/my-project", "aud": "my-project", "sub": "uid-1",
+			"iat": now.Unix(), "exp": now.Add(time.Hour).Unix(), "auth_time": now.Add(-time.Hour).Unix(),
+			"firebase": map[string]interface{}{
+				"sign_in_provider": "google.com",
+				"identities":       map[string][]string{"google.com": {"1234"}},
+			},
+		}
+	}
+
+	token, err := v.VerifyIDToken(ctx, signES256(t, priv, "fb", claims()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Sub != "uid-1" || token.SignInProvider != "google.com" || token.Identities["google.com"][0] != "1234" {
+		t.Errorf("Unexpected token %+v", token)
+	}
+	if token.AuthTime.Unix() != now.Add(-time.Hour).Unix() {
+		t.Errorf("Expect auth_time %v, got %v", now.Add(-time.Hour), token.AuthTime)
+	}
+
+	if _, err = v.VerifySessionCookie(ctx, signES256(t, priv, "fb", claims())); !errors.Is(err, ErrWrongIssuer) {
+		t.Errorf("Expect ErrWrongIssuer for an ID token as session cookie, got %v", err)
+	}
+	cookie := claims()
+	cookie["iss"] = "https://session.firebase.google.com/my-project"
+	cookie["exp"] = now.Add(10 * 24 * time.Hour).Unix()
+	if _, err = v.VerifySessionCookie(ctx, signES256(t, priv, "fb", cookie)); err != nil {
+		t.Error(err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(c map[string]interface{})
+		err    error
+	}{
+		{"other project", func(c map[string]interface{}) { c["aud"] = "other-project" }, ErrWrongAudience},
+		{"no auth_time", func(c map[string]interface{}) { delete(c, "auth_time") }, ErrMissingClaim},
+		{"future auth_time", func(c map[string]interface{}) { c["auth_time"] = now.Add(time.Hour).Unix() }, ErrTokenUsedTooEarly},
+		{"no sub", func(c map[string]interface{}) { delete(c, "sub") }, ErrMissingClaim},
+		{"long sub", func(c map[string]interface{}) { c["sub"] = strings.Repeat("u", 129) }, ErrInvalidToken},
+	}
+	for _, test := range tests {
+		c := claims()
+		test.change(c)
+		if _, err = v.VerifyIDToken(ctx, signES256(t, priv, "fb", c)); !errors.Is(err, test.err) {
+			t.Errorf("%s: expect %v, got %v", test.name, test.err, err)
+		}
+	}
+}
@@ -2,4 +2,12 @@ module github.com/fafg/google-id-verifier
 
 go 1.15
 
-require golang.org/x/oauth2 v0.0.0-20200902213428-5d25da1a8d43
+require (
+	github.com/gin-gonic/gin v1.6.3
+	github.com/gofiber/fiber/v2 v2.2.0
+	github.com/labstack/echo/v4 v4.1.17
+	github.com/prometheus/client_golang v1.7.1
+	github.com/xeipuuv/gojsonschema v1.2.0
+	golang.org/x/oauth2 v0.0.0-20200902213428-5d25da1a8d43
+	google.golang.org/grpc v1.33.2
+)
@@ -0,0 +1,177 @@
+// Package grpcauth authenticates gRPC calls with Google ID tokens, e.g. for service-to-service calls on Cloud Run.
+//
+// The interceptors verify the "authorization: Bearer <token>" metadata of incoming calls and store the
+// claims in the call context, or fail the call with codes.Unauthenticated:
+//
+//	v := googleIDVerifier.NewVerifier(googleIDVerifier.WithAudiences(audience))
+//	srv := grpc.NewServer(
+//		grpc.UnaryInterceptor(grpcauth.UnaryServerInterceptor(v)),
+//		grpc.StreamInterceptor(grpcauth.StreamServerInterceptor(v)),
+//	)
+//	// in handlers:
+//	claimSet, _ := googleIDVerifier.ClaimsFromContext(ctx)
+//
+// An Interceptor overrides the audiences, issuers and claim checks of some methods, e.g. of another
+// OAuth client, sharing one verifier and its cached keys:
+//
+//	auth := &grpcauth.Interceptor{Verifier: v, Methods: map[string]grpcauth.Policy{
+//		"/admin.Admin/Delete": {Audience: []string{adminClientID}, Validate: []func(*googleIDVerifier.ClaimSet) error{staff}},
+//	}}
+//	srv := grpc.NewServer(grpc.UnaryInterceptor(auth.Unary()), grpc.StreamInterceptor(auth.Stream()))
+//
+// Calls of users not allowed, e.g. by a hosted domain check, fail with codes.PermissionDenied.
+package grpcauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+)
+
+// Verifier verifies an ID token for the given audiences
+type Verifier interface {
+	VerifyIDToken(idToken string, audience ...string) (*googleIDVerifier.ClaimSet, error)
+}
+
+// ContextVerifier is a Verifier honoring the context of the call, such as googleIDVerifier.CertsVerifier
+type ContextVerifier interface {
+	VerifyIDTokenContext(ctx context.Context, idToken string, audience ...string) (*googleIDVerifier.ClaimSet, error)
+}
+
+// IssuersVerifier is a verifier accepting other issuers than its own, such as googleIDVerifier.CertsVerifier
+type IssuersVerifier interface {
+	VerifyIDTokenWithIssuersContext(ctx context.Context, idToken string, issuers []string,
+		audience ...string) (*googleIDVerifier.ClaimSet, error)
+}
+
+// ErrIssuersNotSupported is returned when issuers are set for a verifier which is not an IssuersVerifier
+var ErrIssuersNotSupported = errors.New("Verifier does not support issuer overrides")
+
+// Policy is how the calls of a method are authenticated
+type Policy struct {
+	// Audience is passed to the verifier, which uses its default audiences when empty
+	Audience []string
+	// Issuers, if set, are accepted instead of the verifier issuers, which must be an IssuersVerifier
+	Issuers []string
+	// Validate are checks of the verified claims, e.g. a hosted domain
+	Validate []func(claimSet *googleIDVerifier.ClaimSet) error
+}
+
+// Interceptor authenticates calls with Verifier, following the Policy of their method in Methods,
+// keyed by full method name such as "/package.Service/Method", and otherwise Policy
+type Interceptor struct {
+	Verifier Verifier
+	Policy   Policy
+	Methods  map[string]Policy
+	// FailureLogger, if set, logs the rejected calls with a rate limit per error code
+	FailureLogger *googleIDVerifier.FailureLogger
+}
+
+// Unary returns the interceptor of unary calls
+func (i *Interceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := i.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Stream returns the interceptor of streaming calls
+func (i *Interceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := i.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// UnaryServerInterceptor authenticates unary calls with v for the given audiences,
+// or the default audiences of v when none is given
+func UnaryServerInterceptor(v Verifier, audience ...string) grpc.UnaryServerInterceptor {
+	return (&Interceptor{Verifier: v, Policy: Policy{Audience: audience}}).Unary()
+}
+
+// StreamServerInterceptor authenticates streaming calls with v for the given audiences,
+// or the default audiences of v when none is given
+func StreamServerInterceptor(v Verifier, audience ...string) grpc.StreamServerInterceptor {
+	return (&Interceptor{Verifier: v, Policy: Policy{Audience: audience}}).Stream()
+}
+
+// authenticatedStream is a stream whose context carries the verified claims
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (i *Interceptor) authenticate(ctx context.Context, method string) (context.Context, error) {
+	policy, ok := i.Methods[method]
+	if !ok {
+		policy = i.Policy
+	}
+	claimSet, err := i.authorize(ctx, policy)
+	if err != nil {
+		if i.FailureLogger != nil {
+			i.FailureLogger.Log(err)
+		}
+		return nil, reject(err)
+	}
+	return googleIDVerifier.ContextWithClaims(ctx, claimSet), nil
+}
+
+func (i *Interceptor) authorize(ctx context.Context, policy Policy) (*googleIDVerifier.ClaimSet, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	token, err := googleIDVerifier.BearerExtractor().ExtractToken(http.Header{"Authorization": md.Get("authorization")})
+	if err != nil {
+		return nil, err
+	}
+	claimSet, err := i.verify(ctx, token, policy)
+	if err != nil {
+		return nil, err
+	}
+	for _, validate := range policy.Validate {
+		err = validate(claimSet)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return claimSet, nil
+}
+
+func (i *Interceptor) verify(ctx context.Context, token string, policy Policy) (*googleIDVerifier.ClaimSet, error) {
+	if len(policy.Issuers) > 0 {
+		iv, ok := i.Verifier.(IssuersVerifier)
+		if !ok {
+			return nil, ErrIssuersNotSupported
+		}
+		return iv.VerifyIDTokenWithIssuersContext(ctx, token, policy.Issuers, policy.Audience...)
+	}
+	if cv, ok := i.Verifier.(ContextVerifier); ok {
+		return cv.VerifyIDTokenContext(ctx, token, policy.Audience...)
+	}
+	return i.Verifier.VerifyIDToken(token, policy.Audience...)
+}
+
+// reject returns a status with the client-safe description of err: PermissionDenied for users
+// not allowed, Unauthenticated otherwise
+func reject(err error) error {
+	reason := googleIDVerifier.ClientReason(err)
+	if reason == googleIDVerifier.ReasonNotAllowed {
+		return status.Error(codes.PermissionDenied, reason.Description())
+	}
+	return status.Error(codes.Unauthenticated, reason.Description())
+}
@@ -0,0 +1,133 @@
+package grpcauth
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+	"github.com/fafg/google-id-verifier/idptest"
+)
+
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestInterceptors(t *testing.T) {
+	idp, err := idptest.New("client-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idp.Close()
+	v := googleIDVerifier.NewVerifier(
+		googleIDVerifier.WithKeySet(googleIDVerifier.NewKeySet(idp.Certs(), nil)),
+		googleIDVerifier.WithAudiences("client-id"),
+	)
+	token, err := idp.Token(map[string]interface{}{"email": "service@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withToken := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+
+	unary := UnaryServerInterceptor(v)
+	res, err := unary(withToken, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		claimSet, _ := googleIDVerifier.ClaimsFromContext(ctx)
+		return claimSet.Email, nil
+	})
+	if err != nil || res != "service@example.com" {
+		t.Errorf("Expect call to be authenticated, got %v %v", res, err)
+	}
+	_, err = unary(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		t.Error("Expect handler not to be called without a token")
+		return nil, nil
+	})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expect Unauthenticated, got %v", err)
+	}
+
+	stream := StreamServerInterceptor(v)
+	err = stream(nil, &fakeStream{ctx: withToken}, &grpc.StreamServerInfo{}, func(srv interface{}, ss grpc.ServerStream) error {
+		if _, ok := googleIDVerifier.ClaimsFromContext(ss.Context()); !ok {
+			t.Error("Expect claims in the stream context")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	bad := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token+"x"))
+	err = stream(nil, &fakeStream{ctx: bad}, &grpc.StreamServerInfo{}, func(interface{}, grpc.ServerStream) error { return nil })
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expect Unauthenticated, got %v", err)
+	}
+
+	failures := googleIDVerifier.NewFailureLogger(func(string, ...interface{}) {})
+	logged := &Interceptor{Verifier: v, FailureLogger: failures}
+	_, _ = logged.Unary()(bad, nil, &grpc.UnaryServerInfo{}, func(context.Context, interface{}) (interface{}, error) { return nil, nil })
+	if failures.Counts()[googleIDVerifier.ErrWrongSignature.Error()] != 1 {
+		t.Errorf("Expect the rejected call to be logged, got %v", failures.Counts())
+	}
+}
+
+func TestInterceptorMethods(t *testing.T) {
+	idp, err := idptest.New("client-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idp.Close()
+	auth := &Interceptor{
+		Verifier: googleIDVerifier.NewVerifier(
+			googleIDVerifier.WithKeySet(googleIDVerifier.NewKeySet(idp.Certs(), nil)),
+			googleIDVerifier.WithAudiences("client-id"),
+		),
+		Methods: map[string]Policy{
+			"/admin.Admin/Delete": {
+				Audience: []string{"admin-client-id"},
+				Validate: []func(*googleIDVerifier.ClaimSet) error{func(c *googleIDVerifier.ClaimSet) error {
+					return googleIDVerifier.Claims(c).RequireHostedDomain("example.com").Err()
+				}},
+			},
+		},
+	}
+	token, err := idp.Token(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminToken, err := idp.Token(map[string]interface{}{"aud": "admin-client-id", "hd": "example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outsiderToken, err := idp.Token(map[string]interface{}{"aud": "admin-client-id", "hd": "example.org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unary := auth.Unary()
+	for _, test := range []struct {
+		method string
+		token  string
+		code   codes.Code
+	}{
+		{"/api.API/Get", token, codes.OK},
+		{"/api.API/Get", adminToken, codes.Unauthenticated},
+		{"/admin.Admin/Delete", adminToken, codes.OK},
+		{"/admin.Admin/Delete", token, codes.Unauthenticated},
+		{"/admin.Admin/Delete", outsiderToken, codes.PermissionDenied},
+	} {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+test.token))
+		_, err = unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: test.method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		if status.Code(err) != test.code {
+			t.Errorf("Expect %s for %s, got %v", test.code, test.method, err)
+		}
+	}
+}
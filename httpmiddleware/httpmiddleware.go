@@ -0,0 +1,258 @@
+// Package httpmiddleware protects net/http routes with Google ID tokens.
+//
+// The middleware verifies the Bearer token of each request and stores its claims in the request
+// context, so it plugs into the standard library, chi or gorilla/mux alike:
+//
+//	v := googleIDVerifier.NewVerifier(googleIDVerifier.WithAudiences(clientID))
+//	http.Handle("/api/", httpmiddleware.New(v)(api))
+//	// in api:
+//	claimSet, _ := googleIDVerifier.ClaimsFromContext(r.Context())
+//
+// Health checks and preflights can bypass it, and public routes accept anonymous requests:
+//
+//	m := &httpmiddleware.Middleware{
+//		Verifier: v,
+//		Skip:     httpmiddleware.SkipAny(httpmiddleware.SkipPaths("/healthz", "/metrics"), httpmiddleware.SkipPreflight),
+//		Optional: true,
+//	}
+//	http.Handle("/", m.Handler(site))
+//	http.Handle("/account/", m.Handler(httpmiddleware.RequireAuthenticated(account)))
+//
+// Routes serving another OAuth client or with a stricter policy override the middleware, sharing its
+// verifier and the keys it caches:
+//
+//	staff := func(c *googleIDVerifier.ClaimSet) error {
+//		return googleIDVerifier.Claims(c).RequireHostedDomain("example.com").Err()
+//	}
+//	http.Handle("/admin/", m.With(httpmiddleware.WithAudience(adminClientID), httpmiddleware.WithPolicy(staff)).Handler(admin))
+package httpmiddleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+)
+
+// Verifier verifies an ID token for the given audiences
+type Verifier interface {
+	VerifyIDToken(idToken string, audience ...string) (*googleIDVerifier.ClaimSet, error)
+}
+
+// ContextVerifier is a Verifier honoring the context of the request, such as googleIDVerifier.CertsVerifier
+type ContextVerifier interface {
+	VerifyIDTokenContext(ctx context.Context, idToken string, audience ...string) (*googleIDVerifier.ClaimSet, error)
+}
+
+// IssuersVerifier is a Verifier accepting other issuers than its own, such as googleIDVerifier.CertsVerifier
+type IssuersVerifier interface {
+	VerifyIDTokenWithIssuersContext(ctx context.Context, idToken string, issuers []string,
+		audience ...string) (*googleIDVerifier.ClaimSet, error)
+}
+
+// ErrIssuersNotSupported is returned when issuers are set for a verifier which is not an IssuersVerifier
+var ErrIssuersNotSupported = errors.New("Verifier does not support issuer overrides")
+
+// Middleware rejects requests without a valid ID token
+type Middleware struct {
+	Verifier Verifier
+	// Audience is passed to the verifier, which uses its default audiences when empty
+	Audience []string
+	// Issuers, if set, are accepted instead of the verifier issuers, which must be an IssuersVerifier
+	Issuers []string
+	// Policies are checked on the verified claims, e.g. a hosted domain. Errors wrapping an error of
+	// a user not allowed, such as googleIDVerifier.ErrHostedDomainNotAllowed, get a 403.
+	Policies []func(claimSet *googleIDVerifier.ClaimSet) error
+	// Extractor finds the token in requests, googleIDVerifier.BearerExtractor when nil
+	Extractor googleIDVerifier.TokenExtractor
+	// Skip, if set, lets through without verification the requests for which it returns true,
+	// e.g. health checks, see SkipPaths and SkipPreflight
+	Skip func(r *http.Request) bool
+	// Optional lets through the requests without a token as anonymous, see IsAnonymous, while the requests
+	// with an invalid token are still rejected. RequireAuthenticated protects their private routes.
+	Optional bool
+	// OnError, if set, writes the response of rejected requests instead of a 401, or 403 for users not
+	// allowed, with client-safe problem details and a Challenge, see googleIDVerifier.WriteRejection
+	OnError func(w http.ResponseWriter, r *http.Request, err error)
+	// FailureLogger, if set, logs the rejections with a rate limit per error code
+	FailureLogger *googleIDVerifier.FailureLogger
+}
+
+// New returns a middleware function verifying tokens with v for the given audiences
+func New(v Verifier, audience ...string) func(http.Handler) http.Handler {
+	m := &Middleware{Verifier: v, Audience: audience}
+	return m.Handler
+}
+
+// Handler verifies the token of each request and calls next with its claims in the request context,
+// see googleIDVerifier.ClaimsFromContext
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	extractor := m.Extractor
+	if extractor == nil {
+		extractor = googleIDVerifier.BearerExtractor()
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.Skip != nil && m.Skip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, err := extractor.ExtractToken(r.Header)
+		if errors.Is(err, googleIDVerifier.ErrNoToken) && m.Optional {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), anonymousKey{}, true)))
+			return
+		}
+		if err != nil {
+			m.reject(w, r, err)
+			return
+		}
+		claimSet, err := m.verify(r.Context(), token)
+		if err != nil {
+			m.reject(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(googleIDVerifier.ContextWithClaims(r.Context(), claimSet)))
+	})
+}
+
+type anonymousKey struct{}
+
+// IsAnonymous reports whether the request was let through without a token by an Optional middleware
+func IsAnonymous(ctx context.Context) bool {
+	anonymous, _ := ctx.Value(anonymousKey{}).(bool)
+	return anonymous
+}
+
+// RequireAuthenticated rejects the requests without verified claims, e.g. the anonymous ones let through
+// by an Optional middleware, with a 401 and a Bearer challenge
+func RequireAuthenticated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := googleIDVerifier.ClaimsFromContext(r.Context()); !ok {
+			w.Header().Set("WWW-Authenticate", Challenge(googleIDVerifier.ErrNoToken))
+			googleIDVerifier.WriteRejection(w, googleIDVerifier.ErrNoToken)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SkipPaths returns a Skip function matching the requests for the given paths, e.g. "/healthz".
+// Paths ending with a slash match every path below them, e.g. "/metrics/".
+func SkipPaths(paths ...string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		for _, path := range paths {
+			if r.URL.Path == path || (strings.HasSuffix(path, "/") && strings.HasPrefix(r.URL.Path, path)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// SkipPreflight is a Skip function matching the CORS preflight requests, which browsers send without credentials
+func SkipPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// SkipAny returns a Skip function matching the requests matched by any of skips
+func SkipAny(skips ...func(r *http.Request) bool) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		for _, skip := range skips {
+			if skip(r) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Option overrides the policy of a Middleware for some routes, see Middleware.With
+type Option func(m *Middleware)
+
+// WithAudience overrides the audiences passed to the verifier
+func WithAudience(audience ...string) Option {
+	return func(m *Middleware) {
+		m.Audience = audience
+	}
+}
+
+// WithIssuers overrides the accepted issuers, see Middleware.Issuers
+func WithIssuers(issuers ...string) Option {
+	return func(m *Middleware) {
+		m.Issuers = issuers
+	}
+}
+
+// WithPolicy adds a check of the verified claims to the ones of the middleware
+func WithPolicy(policy func(claimSet *googleIDVerifier.ClaimSet) error) Option {
+	return func(m *Middleware) {
+		m.Policies = append(m.Policies[:len(m.Policies):len(m.Policies)], policy)
+	}
+}
+
+// With returns a copy of the middleware with the given overrides, e.g. for the routes of another OAuth
+// client, sharing its verifier and so the keys it caches instead of constructing one verifier per route
+func (m *Middleware) With(opts ...Option) *Middleware {
+	route := *m
+	for _, opt := range opts {
+		opt(&route)
+	}
+	return &route
+}
+
+func (m *Middleware) verify(ctx context.Context, token string) (*googleIDVerifier.ClaimSet, error) {
+	claimSet, err := m.verifyToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	for _, policy := range m.Policies {
+		err = policy(claimSet)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return claimSet, nil
+}
+
+func (m *Middleware) verifyToken(ctx context.Context, token string) (*googleIDVerifier.ClaimSet, error) {
+	if len(m.Issuers) > 0 {
+		iv, ok := m.Verifier.(IssuersVerifier)
+		if !ok {
+			return nil, ErrIssuersNotSupported
+		}
+		return iv.VerifyIDTokenWithIssuersContext(ctx, token, m.Issuers, m.Audience...)
+	}
+	if cv, ok := m.Verifier.(ContextVerifier); ok {
+		return cv.VerifyIDTokenContext(ctx, token, m.Audience...)
+	}
+	return m.Verifier.VerifyIDToken(token, m.Audience...)
+}
+
+func (m *Middleware) reject(w http.ResponseWriter, r *http.Request, err error) {
+	if m.FailureLogger != nil {
+		m.FailureLogger.Log(err)
+	}
+	if m.OnError != nil {
+		m.OnError(w, r, err)
+		return
+	}
+	w.Header().Set("WWW-Authenticate", Challenge(err))
+	googleIDVerifier.WriteRejection(w, err)
+}
+
+// Challenge returns the RFC 6750 WWW-Authenticate header of a request rejected with err: a bare Bearer
+// challenge when the request has no token, insufficient_scope for users not allowed and otherwise
+// invalid_token, with the client-safe description of err
+func Challenge(err error) string {
+	if errors.Is(err, googleIDVerifier.ErrNoToken) {
+		return "Bearer"
+	}
+	reason := googleIDVerifier.ClientReason(err)
+	code := "invalid_token"
+	if reason == googleIDVerifier.ReasonNotAllowed {
+		code = "insufficient_scope"
+	}
+	return fmt.Sprintf(`Bearer error="%s", error_description="%s"`, code, reason.Description())
+}
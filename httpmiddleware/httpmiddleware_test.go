@@ -0,0 +1,206 @@
+package httpmiddleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+	"github.com/fafg/google-id-verifier/idptest"
+)
+
+func TestMiddleware(t *testing.T) {
+	idp, err := idptest.New("client-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idp.Close()
+	v := googleIDVerifier.NewVerifier(
+		googleIDVerifier.WithKeySet(googleIDVerifier.NewKeySetFromSource(googleIDVerifier.URLKeySource(idp.JWKSURL(), nil))),
+		googleIDVerifier.WithAudiences("client-id"),
+	)
+	h := New(v)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claimSet, ok := googleIDVerifier.ClaimsFromContext(r.Context())
+		if !ok {
+			t.Error("Expect claims in the request context")
+			return
+		}
+		_, _ = w.Write([]byte(claimSet.Email))
+	}))
+	get := func(authorization string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api", nil)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	token, err := idp.Token(map[string]interface{}{"email": "user@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := get("Bearer " + token); rec.Code != http.StatusOK || rec.Body.String() != "user@example.com" {
+		t.Errorf("Expect token to be accepted, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	for authorization, challenge := range map[string]string{
+		"":                      "Bearer",
+		"Bearer " + token + "x": `Bearer error="invalid_token", error_description="The token is invalid."`,
+	} {
+		rec := get(authorization)
+		problem := &googleIDVerifier.Problem{}
+		_ = json.NewDecoder(rec.Body).Decode(problem)
+		if rec.Code != http.StatusUnauthorized || problem.Code == "" || rec.Header().Get("WWW-Authenticate") != challenge {
+			t.Errorf("Expect a problem+json 401 for %q, got %d %v %s", authorization, rec.Code, problem, rec.Header().Get("WWW-Authenticate"))
+		}
+	}
+
+	failures := googleIDVerifier.NewFailureLogger(func(string, ...interface{}) {})
+	h = (&Middleware{Verifier: v, FailureLogger: failures}).Handler(http.NotFoundHandler())
+	_ = get("")
+	_ = get("Bearer " + token + "x")
+	if counts := failures.Counts(); counts[googleIDVerifier.ErrNoToken.Error()] != 1 || counts[googleIDVerifier.ErrWrongSignature.Error()] != 1 {
+		t.Errorf("Expect the rejections to be logged, got %v", counts)
+	}
+
+	h = New(googleIDVerifier.NewVerifier(
+		googleIDVerifier.WithKeySet(googleIDVerifier.NewKeySetFromSource(googleIDVerifier.URLKeySource(idp.JWKSURL(), nil))),
+		googleIDVerifier.WithAudiences("client-id"),
+		googleIDVerifier.WithAllowedHostedDomains("example.org"),
+	))(http.NotFoundHandler())
+	rec := get("Bearer " + token)
+	if rec.Code != http.StatusForbidden ||
+		!strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), `Bearer error="insufficient_scope"`) {
+		t.Errorf("Expect a 403 for a user not allowed, got %d %s", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestMiddlewareSkipAndOptional(t *testing.T) {
+	idp, err := idptest.New("client-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idp.Close()
+	m := &Middleware{
+		Verifier: googleIDVerifier.NewVerifier(
+			googleIDVerifier.WithKeySet(googleIDVerifier.NewKeySetFromSource(googleIDVerifier.URLKeySource(idp.JWKSURL(), nil))),
+			googleIDVerifier.WithAudiences("client-id"),
+		),
+		Skip:     SkipAny(SkipPaths("/healthz", "/metrics/"), SkipPreflight),
+		Optional: true,
+	}
+	h := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := googleIDVerifier.ClaimsFromContext(r.Context()); ok {
+			w.WriteHeader(http.StatusAccepted)
+		} else if IsAnonymous(r.Context()) {
+			w.WriteHeader(http.StatusNonAuthoritativeInfo)
+		}
+	}))
+	token, err := idp.Token(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		method        string
+		path          string
+		authorization string
+		code          int
+	}{
+		{http.MethodGet, "/healthz", "Bearer garbage", http.StatusOK},
+		{http.MethodGet, "/metrics/go", "Bearer garbage", http.StatusOK},
+		{http.MethodGet, "/metricsx", "Bearer garbage", http.StatusUnauthorized},
+		{http.MethodOptions, "/api", "", http.StatusOK},
+		{http.MethodGet, "/api", "", http.StatusNonAuthoritativeInfo},
+		{http.MethodGet, "/api", "Bearer garbage", http.StatusUnauthorized},
+		{http.MethodGet, "/api", "Bearer " + token, http.StatusAccepted},
+	} {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		if test.method == http.MethodOptions {
+			r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		}
+		if test.authorization != "" {
+			r.Header.Set("Authorization", test.authorization)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code != test.code {
+			t.Errorf("Expect %d for %s %s %q, got %d", test.code, test.method, test.path, test.authorization, rec.Code)
+		}
+	}
+}
+
+func TestRequireAuthenticated(t *testing.T) {
+	h := RequireAuthenticated(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/account", nil)
+	h.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), anonymousKey{}, true)))
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") != "Bearer" {
+		t.Errorf("Expect anonymous requests to get a 401, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, r.WithContext(googleIDVerifier.ContextWithClaims(r.Context(), &googleIDVerifier.ClaimSet{})))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expect authenticated requests to go through, got %d", rec.Code)
+	}
+}
+
+func TestMiddlewareWith(t *testing.T) {
+	idp, err := idptest.New("client-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idp.Close()
+	m := &Middleware{Verifier: googleIDVerifier.NewVerifier(
+		googleIDVerifier.WithKeySet(googleIDVerifier.NewKeySet(idp.Certs(), nil)),
+		googleIDVerifier.WithAudiences("client-id"),
+	)}
+	admin := m.With(WithAudience("admin-client-id"), WithPolicy(func(c *googleIDVerifier.ClaimSet) error {
+		return googleIDVerifier.Claims(c).RequireHostedDomain("example.com").Err()
+	}))
+	other := m.With(WithIssuers("https://other.example.com"))
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	token, err := idp.Token(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminToken, err := idp.Token(map[string]interface{}{"aud": "admin-client-id", "hd": "example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outsiderToken, err := idp.Token(map[string]interface{}{"aud": "admin-client-id", "hd": "example.org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name  string
+		m     *Middleware
+		token string
+		code  int
+	}{
+		{"default", m, token, http.StatusOK},
+		{"default with admin token", m, adminToken, http.StatusUnauthorized},
+		{"admin", admin, adminToken, http.StatusOK},
+		{"admin with default token", admin, token, http.StatusUnauthorized},
+		{"admin with outsider", admin, outsiderToken, http.StatusForbidden},
+		{"other issuer", other, token, http.StatusUnauthorized},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/api", nil)
+		r.Header.Set("Authorization", "Bearer "+test.token)
+		rec := httptest.NewRecorder()
+		test.m.Handler(ok).ServeHTTP(rec, r)
+		if rec.Code != test.code {
+			t.Errorf("Expect %d for %s, got %d", test.code, test.name, rec.Code)
+		}
+	}
+	if len(m.Audience) != 0 || len(m.Policies) != 0 {
+		t.Error("Expect overrides not to change the middleware")
+	}
+}
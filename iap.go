@@ -0,0 +1,69 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// Keys signing the Identity-Aware Proxy assertions
+	iapCertsURL = "https://www.gstatic.com/iap/verify/public_key-jwk"
+)
+
+const (
+	// IAPHeader is the header carrying the Identity-Aware Proxy assertion of the requests it forwards
+	IAPHeader = "x-goog-iap-jwt-assertion"
+
+	iapIssuer    = "https://cloud.google.com/iap"
+	iapAlgorithm = es256
+)
+
+// IAPAppEngineAudience returns the audience of the assertions for an App Engine app
+func IAPAppEngineAudience(projectNumber string, projectID string) string {
+	return fmt.Sprintf("/projects/%s/apps/%s", projectNumber, projectID)
+}
+
+// IAPBackendServiceAudience returns the audience of the assertions for a Compute Engine or GKE backend service
+func IAPBackendServiceAudience(projectNumber string, backendServiceID string) string {
+	return fmt.Sprintf("/projects/%s/global/backendServices/%s", projectNumber, backendServiceID)
+}
+
+// IAPExtractor extracts the Identity-Aware Proxy assertion from the IAPHeader
+func IAPExtractor() TokenExtractor {
+	return HeaderExtractor(IAPHeader)
+}
+
+// IAPVerifier verifies the assertions Identity-Aware Proxy adds to the requests it forwards,
+// so services behind it can authenticate users locally. It plugs into the httpmiddleware package
+// with IAPExtractor:
+//
+//	v := googleIDVerifier.NewIAPVerifier(googleIDVerifier.IAPBackendServiceAudience("123", "456"))
+//	m := &httpmiddleware.Middleware{Verifier: v, Extractor: googleIDVerifier.IAPExtractor()}
+type IAPVerifier struct {
+	*CertsVerifier
+}
+
+// NewIAPVerifier returns an IAPVerifier accepting assertions for audience, see IAPAppEngineAudience
+// and IAPBackendServiceAudience. Assertions not signed with ES256 return ErrUnsupportedAlgorithm.
+func NewIAPVerifier(audience string, opts ...Option) *IAPVerifier {
+	v := &CertsVerifier{}
+	for _, opt := range append([]Option{WithIssuers(iapIssuer), WithAudiences(audience), WithAllowedAlgorithms(iapAlgorithm)}, opts...) {
+		opt(v)
+	}
+	if v.KeySet == nil {
+		v.KeySet = NewKeySetContext(nil, func(ctx context.Context) (*Certs, error) {
+			return fetchCerts(ctx, v.HTTPClient, iapCertsURL)
+		})
+	}
+	return &IAPVerifier{CertsVerifier: v}
+}
+
+// VerifyRequest checks the assertion in the IAPHeader of r
+func (v *IAPVerifier) VerifyRequest(r *http.Request) (*ClaimSet, error) {
+	assertion, err := IAPExtractor().ExtractToken(r.Header)
+	if err != nil {
+		return nil, err
+	}
+	return v.VerifyIDTokenContext(r.Context(), assertion)
+}
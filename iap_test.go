@@ -0,0 +1,58 @@
+package googleIDVerifier
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIAPVerifier(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(response{Keys: []*key{encodeECKey("iap", &priv.PublicKey)}})
+	}))
+	defer srv.Close()
+	defer func(url string) { iapCertsURL = url }(iapCertsURL)
+	iapCertsURL = srv.URL
+
+	aud := IAPBackendServiceAudience("123", "456")
+	if aud != "/projects/123/global/backendServices/456" {
+		t.Errorf("Unexpected audience %s", aud)
+	}
+	v := NewIAPVerifier(aud)
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": "https://cloud.google.com/iap", "aud": aud, "sub": "accounts.google.com:1", "email": "user@example.com",
+		"iat": now.Unix(), "exp": now.Add(10 * time.Minute).Unix(),
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, err = v.VerifyRequest(r); !errors.Is(err, ErrNoToken) {
+		t.Errorf("Expect ErrNoToken without assertion, got %v", err)
+	}
+	r.Header.Set(IAPHeader, signES256(t, priv, "iap", claims))
+	claimSet, err := v.VerifyRequest(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimSet.Email != "user@example.com" {
+		t.Errorf("Expect user@example.com, got %s", claimSet.Email)
+	}
+
+	claims["aud"] = IAPAppEngineAudience("123", "my-app")
+	if _, err = v.VerifyIDToken(signES256(t, priv, "iap", claims)); !errors.Is(err, ErrWrongAudience) {
+		t.Errorf("Expect ErrWrongAudience for another app, got %v", err)
+	}
+	if _, err = v.VerifyIDToken(validTestToken); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("Expect ErrUnsupportedAlgorithm for an RS256 token, got %v", err)
+	}
+}
@@ -0,0 +1,291 @@
+// Package idptest provides a miniature Google-like identity provider for end-to-end tests.
+//
+// It serves JWKS and PEM certs, an authorization endpoint which immediately redirects back with a code,
+// and a token endpoint exchanging that code for a signed ID token. Keys can be rotated on demand.
+//
+//	idp, _ := idptest.New("client-id")
+//	defer idp.Close()
+//	token, _ := idp.Token(map[string]interface{}{"email": "user@example.com"})
+//	claims, err := googleIDVerifier.VerifySignedJWTWithCerts(token, idp.Certs(), []string{"client-id"},
+//		googleIDVerifier.Issuers, googleIDVerifier.MaxTokenLifetime)
+package idptest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+)
+
+const (
+	// Issuer is the iss claim of issued tokens, accepted by the default googleIDVerifier.Issuers
+	Issuer = "https://accounts.google.com"
+
+	jwksPath  = "/oauth2/v3/certs"
+	pemPath   = "/oauth2/v1/certs"
+	authPath  = "/o/oauth2/v2/auth"
+	tokenPath = "/token"
+)
+
+// IDP is a fake identity provider backed by an httptest.Server
+type IDP struct {
+	*httptest.Server
+
+	// ClientID is the default aud claim of issued tokens
+	ClientID string
+	// Lifetime of issued tokens, one hour by default
+	Lifetime time.Duration
+
+	mu    sync.Mutex
+	keys  []*signingKey
+	codes map[string]map[string]interface{}
+}
+
+type signingKey struct {
+	kid  string
+	key  *rsa.PrivateKey
+	cert []byte
+}
+
+// New starts a fake identity provider issuing tokens for the given client ID
+func New(clientID string) (*IDP, error) {
+	p := &IDP{
+		ClientID: clientID,
+		Lifetime: time.Hour,
+		codes:    map[string]map[string]interface{}{},
+	}
+	err := p.Rotate()
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(jwksPath, p.serveJWKS)
+	mux.HandleFunc(pemPath, p.servePEM)
+	mux.HandleFunc(authPath, p.serveAuth)
+	mux.HandleFunc(tokenPath, p.serveToken)
+	p.Server = httptest.NewServer(mux)
+	return p, nil
+}
+
+// JWKSURL is the URL serving the public keys in JWKS format
+func (p *IDP) JWKSURL() string { return p.URL + jwksPath }
+
+// PEMURL is the URL serving the public keys as x509 PEM certs keyed by kid
+func (p *IDP) PEMURL() string { return p.URL + pemPath }
+
+// AuthURL is the authorization endpoint
+func (p *IDP) AuthURL() string { return p.URL + authPath }
+
+// TokenURL is the token endpoint
+func (p *IDP) TokenURL() string { return p.URL + tokenPath }
+
+// Rotate generates a new signing key. The previous key is still served so
+// tokens it signed keep verifying, older keys are dropped.
+func (p *IDP) Rotate() error {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	kidBytes := make([]byte, 20)
+	_, err = rand.Read(kidBytes)
+	if err != nil {
+		return err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "idptest"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = append([]*signingKey{{kid: hex.EncodeToString(kidBytes), key: key, cert: cert}}, p.keys...)
+	if len(p.keys) > 2 {
+		p.keys = p.keys[:2]
+	}
+	return nil
+}
+
+// KeyID returns the kid of the current signing key
+func (p *IDP) KeyID() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.keys[0].kid
+}
+
+// Certs returns the public keys currently served
+func (p *IDP) Certs() *googleIDVerifier.Certs {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range p.keys {
+		keys[k.kid] = &k.key.PublicKey
+	}
+	return &googleIDVerifier.Certs{Keys: keys, Expiry: time.Now().Add(time.Hour)}
+}
+
+// Token issues an ID token signed with the current key. The given claims are
+// merged over the defaults: iss, aud, sub, email, email_verified, iat and exp.
+func (p *IDP) Token(claims map[string]interface{}) (string, error) {
+	now := time.Now()
+	payload := map[string]interface{}{
+		"iss":            Issuer,
+		"aud":            p.ClientID,
+		"azp":            p.ClientID,
+		"sub":            "1234567890",
+		"email":          "user@example.com",
+		"email_verified": true,
+		"iat":            now.Unix(),
+		"exp":            now.Add(p.Lifetime).Unix(),
+	}
+	for k, v := range claims {
+		payload[k] = v
+	}
+
+	p.mu.Lock()
+	key := p.keys[0]
+	p.mu.Unlock()
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": key.kid})
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	sum := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func (p *IDP) serveJWKS(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	keys := make([]map[string]string, 0, len(p.keys))
+	for _, k := range p.keys {
+		keys = append(keys, map[string]string{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": k.kid,
+			"n":   base64.RawURLEncoding.EncodeToString(k.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.key.E)).Bytes()),
+		})
+	}
+	p.mu.Unlock()
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	writeJSON(w, map[string]interface{}{"keys": keys})
+}
+
+func (p *IDP) servePEM(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	certs := map[string]string{}
+	for _, k := range p.keys {
+		certs[k.kid] = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: k.cert}))
+	}
+	p.mu.Unlock()
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	writeJSON(w, certs)
+}
+
+// serveAuth immediately "logs in" and redirects back with a code. login_hint, when given,
+// is used as the email of the issued token and nonce is copied into it.
+func (p *IDP) serveAuth(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirect.String() == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	claims := map[string]interface{}{}
+	if clientID := q.Get("client_id"); clientID != "" {
+		claims["aud"] = clientID
+		claims["azp"] = clientID
+	}
+	if hint := q.Get("login_hint"); hint != "" {
+		claims["email"] = hint
+	}
+	if nonce := q.Get("nonce"); nonce != "" {
+		claims["nonce"] = nonce
+	}
+
+	codeBytes := make([]byte, 16)
+	_, err = rand.Read(codeBytes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	code := hex.EncodeToString(codeBytes)
+	p.mu.Lock()
+	p.codes[code] = claims
+	p.mu.Unlock()
+
+	values := redirect.Query()
+	values.Set("code", code)
+	if state := q.Get("state"); state != "" {
+		values.Set("state", state)
+	}
+	redirect.RawQuery = values.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *IDP) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.PostFormValue("grant_type") != "authorization_code" {
+		writeJSONStatus(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	code := r.PostFormValue("code")
+	p.mu.Lock()
+	claims, ok := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+	if !ok {
+		writeJSONStatus(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	idToken, err := p.Token(claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"access_token": "idptest-" + code,
+		"token_type":   "Bearer",
+		"expires_in":   int(p.Lifetime.Seconds()),
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	writeJSONStatus(w, http.StatusOK, v)
+}
+
+// writeJSONStatus sets the headers before WriteHeader, which would otherwise drop them
+func writeJSONStatus(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
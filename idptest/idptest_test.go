@@ -0,0 +1,138 @@
+package idptest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+)
+
+func verify(idp *IDP, token string) (*googleIDVerifier.ClaimSet, error) {
+	return googleIDVerifier.VerifySignedJWTWithCerts(token, idp.Certs(), []string{idp.ClientID},
+		googleIDVerifier.Issuers, googleIDVerifier.MaxTokenLifetime)
+}
+
+func TestToken(t *testing.T) {
+	idp, err := New("client-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idp.Close()
+
+	token, err := idp.Token(map[string]interface{}{"email": "someone@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimSet, err := verify(idp, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimSet.Email != "someone@example.com" {
+		t.Errorf("Invalid Email: %s", claimSet.Email)
+	}
+
+	err = idp.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = verify(idp, token); err != nil {
+		t.Errorf("Expect token signed with previous key to verify, got %v", err)
+	}
+	_ = idp.Rotate()
+	if _, err = verify(idp, token); err != googleIDVerifier.ErrPublicKeyNotFound {
+		t.Errorf("Expect ErrPublicKeyNotFound after two rotations, got %v", err)
+	}
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	idp, err := New("client-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idp.Close()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(idp.AuthURL() + "?" + url.Values{
+		"client_id":    {"client-id"},
+		"redirect_uri": {"https://app.example.com/callback"},
+		"state":        {"xyz"},
+		"nonce":        {"n-0S6"},
+		"login_hint":   {"hint@example.com"},
+	}.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.Query().Get("state") != "xyz" {
+		t.Errorf("Expect state to be passed back, got %s", location)
+	}
+
+	resp, err = http.PostForm(idp.TokenURL(), url.Values{
+		"grant_type": {"authorization_code"},
+		"code":       {location.Query().Get("code")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		IDToken string `json:"id_token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimSet, err := verify(idp, body.IDToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimSet.Email != "hint@example.com" {
+		t.Errorf("Invalid Email: %s", claimSet.Email)
+	}
+
+	// the code was used
+	replayed, err := http.PostForm(idp.TokenURL(), url.Values{
+		"grant_type": {"authorization_code"},
+		"code":       {location.Query().Get("code")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replayed.Body.Close()
+	if replayed.StatusCode != http.StatusBadRequest || replayed.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Expect a JSON 400, got %d %s", replayed.StatusCode, replayed.Header.Get("Content-Type"))
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	idp, err := New("client-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idp.Close()
+
+	resp, err := http.Get(idp.JWKSURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+		} `json:"keys"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&jwks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jwks.Keys) != 1 || jwks.Keys[0].Kid != idp.KeyID() {
+		t.Errorf("Expect current kid to be served, got %+v", jwks.Keys)
+	}
+}
@@ -0,0 +1,168 @@
+// Package internaltoken mints short-lived internal JWTs from verified Google ID tokens, so that
+// Google tokens never travel past the edge of a service mesh.
+//
+//	keys, _ := internaltoken.NewRotatingKeyProvider()
+//	issuer := &internaltoken.Issuer{Issuer: "https://edge.internal", Audience: "mesh", Keys: keys}
+//	claimSet, err := verifier.VerifyIDToken(googleToken)
+//	internal, err := issuer.Mint(claimSet)
+//	http.Handle("/.well-known/jwks.json", issuer.JWKSHandler())
+package internaltoken
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+	"golang.org/x/oauth2/jws"
+)
+
+// DefaultLifetime of minted tokens
+const DefaultLifetime = 5 * time.Minute
+
+// KeyProvider supplies the app-owned keys, e.g. backed by a KMS or a secret store
+type KeyProvider interface {
+	// SigningKey returns the key new tokens are signed with
+	SigningKey() (kid string, key *rsa.PrivateKey, err error)
+	// PublicKeys returns every key tokens may still be signed with, keyed by kid
+	PublicKeys() (map[string]*rsa.PublicKey, error)
+}
+
+// Issuer mints internal tokens from verified claims
+type Issuer struct {
+	// Issuer and Audience are the iss and aud claims of minted tokens
+	Issuer   string
+	Audience string
+	// Lifetime of minted tokens, DefaultLifetime when zero
+	Lifetime time.Duration
+	Keys     KeyProvider
+	// MapClaims returns the private claims of the minted token, by default email and hd
+	MapClaims func(claimSet *googleIDVerifier.ClaimSet) map[string]interface{}
+}
+
+// Mint returns a signed internal token for the verified claims, with the same subject
+func (i *Issuer) Mint(claimSet *googleIDVerifier.ClaimSet) (string, error) {
+	kid, key, err := i.Keys.SigningKey()
+	if err != nil {
+		return "", err
+	}
+	lifetime := i.Lifetime
+	if lifetime == 0 {
+		lifetime = DefaultLifetime
+	}
+	mapClaims := i.MapClaims
+	if mapClaims == nil {
+		mapClaims = defaultClaims
+	}
+	now := time.Now()
+	return jws.Encode(&jws.Header{Algorithm: "RS256", Typ: "JWT", KeyID: kid}, &jws.ClaimSet{
+		Iss:           i.Issuer,
+		Aud:           i.Audience,
+		Sub:           claimSet.Sub,
+		Iat:           now.Unix(),
+		Exp:           now.Add(lifetime).Unix(),
+		PrivateClaims: mapClaims(claimSet),
+	}, key)
+}
+
+// Certs returns the public keys as Certs, to verify internal tokens with googleIDVerifier
+func (i *Issuer) Certs() (*googleIDVerifier.Certs, error) {
+	keys, err := i.Keys.PublicKeys()
+	if err != nil {
+		return nil, err
+	}
+	return &googleIDVerifier.Certs{Keys: keys, Expiry: time.Now().Add(time.Minute)}, nil
+}
+
+// JWKSHandler serves the public keys in JWKS format
+func (i *Issuer) JWKSHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys, err := i.Keys.PublicKeys()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jwks := make([]map[string]string, 0, len(keys))
+		for kid, key := range keys {
+			jwks = append(jwks, map[string]string{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": kid,
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": jwks})
+	})
+}
+
+func defaultClaims(claimSet *googleIDVerifier.ClaimSet) map[string]interface{} {
+	claims := map[string]interface{}{"email": claimSet.Email}
+	if claimSet.HostedDomain != "" {
+		claims["hd"] = claimSet.HostedDomain
+	}
+	return claims
+}
+
+// RotatingKeyProvider keeps generated keys in memory. After a rotation the previous key
+// is still published so tokens it signed keep verifying until they expire.
+type RotatingKeyProvider struct {
+	mu       sync.RWMutex
+	current  string
+	keys     map[string]*rsa.PrivateKey
+	previous string
+}
+
+// NewRotatingKeyProvider returns a provider with a freshly generated key
+func NewRotatingKeyProvider() (*RotatingKeyProvider, error) {
+	p := &RotatingKeyProvider{keys: map[string]*rsa.PrivateKey{}}
+	return p, p.Rotate()
+}
+
+// Rotate generates a new signing key and drops the one before the current key
+func (p *RotatingKeyProvider) Rotate() error {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	kidBytes := make([]byte, 16)
+	_, err = rand.Read(kidBytes)
+	if err != nil {
+		return err
+	}
+	kid := hex.EncodeToString(kidBytes)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.keys, p.previous)
+	p.previous, p.current = p.current, kid
+	p.keys[kid] = key
+	return nil
+}
+
+// SigningKey returns the current key
+func (p *RotatingKeyProvider) SigningKey() (string, *rsa.PrivateKey, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.current, p.keys[p.current], nil
+}
+
+// PublicKeys returns the current and previous public keys
+func (p *RotatingKeyProvider) PublicKeys() (map[string]*rsa.PublicKey, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	keys := map[string]*rsa.PublicKey{}
+	for kid, key := range p.keys {
+		keys[kid] = &key.PublicKey
+	}
+	return keys, nil
+}
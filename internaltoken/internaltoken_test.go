@@ -0,0 +1,60 @@
+package internaltoken
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+)
+
+func TestMint(t *testing.T) {
+	keys, err := NewRotatingKeyProvider()
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &Issuer{Issuer: "https://edge.internal", Audience: "mesh", Keys: keys}
+
+	claimSet := &googleIDVerifier.ClaimSet{Email: "user@example.com", HostedDomain: "example.com"}
+	claimSet.Sub = "1234"
+	token, err := issuer.Mint(claimSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verify := func() (*googleIDVerifier.ClaimSet, error) {
+		certs, err := issuer.Certs()
+		if err != nil {
+			return nil, err
+		}
+		return googleIDVerifier.VerifySignedJWTWithCerts(token, certs, []string{"mesh"},
+			[]string{"https://edge.internal"}, DefaultLifetime)
+	}
+	internal, err := verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if internal.Sub != "1234" || internal.Email != "user@example.com" || internal.HostedDomain != "example.com" {
+		t.Errorf("Unexpected claims %+v", internal)
+	}
+
+	_ = keys.Rotate()
+	if _, err = verify(); err != nil {
+		t.Errorf("Expect previous key to still verify, got %v", err)
+	}
+	_ = keys.Rotate()
+	if _, err = verify(); err != googleIDVerifier.ErrPublicKeyNotFound {
+		t.Errorf("Expect ErrPublicKeyNotFound, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	issuer.JWKSHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	var jwks struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	_ = json.NewDecoder(rec.Body).Decode(&jwks)
+	if len(jwks.Keys) != 2 {
+		t.Errorf("Expect current and previous keys to be served, got %d", len(jwks.Keys))
+	}
+}
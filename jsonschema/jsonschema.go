@@ -0,0 +1,48 @@
+// Package jsonschema validates ID token claims against a JSON Schema.
+//
+// A Schema can be attached to googleIDVerifier.CertsVerifier as its PayloadValidator:
+//
+//	schema, err := jsonschema.New([]byte(`{"required": ["roles"]}`))
+//	v := googleIDVerifier.CertsVerifier{PayloadValidator: schema}
+package jsonschema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// ErrSchemaMismatch is returned when the claims do not satisfy the schema
+var ErrSchemaMismatch = errors.New("Claims do not match schema")
+
+// Schema is a compiled JSON Schema for token claims
+type Schema struct {
+	schema *gojsonschema.Schema
+}
+
+// New compiles the given JSON Schema document
+func New(schema []byte) (*Schema, error) {
+	s, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(schema))
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{schema: s}, nil
+}
+
+// ValidatePayload checks the raw JSON claims payload against the schema
+func (s *Schema) ValidatePayload(payload []byte) error {
+	result, err := s.schema.Validate(gojsonschema.NewBytesLoader(payload))
+	if err != nil {
+		return err
+	}
+	if result.Valid() {
+		return nil
+	}
+	details := make([]string, 0, len(result.Errors()))
+	for _, e := range result.Errors() {
+		details = append(details, e.String())
+	}
+	return fmt.Errorf("%w: %s", ErrSchemaMismatch, strings.Join(details, "; "))
+}
@@ -0,0 +1,36 @@
+package jsonschema
+
+import (
+	"errors"
+	"testing"
+)
+
+const rolesSchema = `{
+	"type": "object",
+	"required": ["sub", "roles"],
+	"properties": {
+		"roles": {"type": "array", "items": {"type": "string"}}
+	}
+}`
+
+func TestValidatePayload(t *testing.T) {
+	s, err := New([]byte(rolesSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.ValidatePayload([]byte(`{"sub": "1", "roles": ["admin"]}`))
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = s.ValidatePayload([]byte(`{"sub": "1", "roles": [1]}`))
+	if !errors.Is(err, ErrSchemaMismatch) {
+		t.Errorf("Expect ErrSchemaMismatch, got %v", err)
+	}
+
+	err = s.ValidatePayload([]byte(`{"sub": "1"}`))
+	if !errors.Is(err, ErrSchemaMismatch) {
+		t.Errorf("Expect ErrSchemaMismatch, got %v", err)
+	}
+}
@@ -3,7 +3,6 @@ package googleIDVerifier
 import (
 	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"strings"
 	"time"
 
@@ -19,7 +18,7 @@ var (
 func parseJWT(token string) (*jws.Header, *ClaimSet, error) {
 	s := strings.Split(token, ".")
 	if len(s) != 3 {
-		return nil, nil, errors.New("Invalid token received")
+		return nil, nil, ErrInvalidToken
 	}
 	decodedHeader, err := base64.RawURLEncoding.DecodeString(s[0])
 	if err != nil {
@@ -37,7 +36,31 @@ func parseJWT(token string) (*jws.Header, *ClaimSet, error) {
 	return header, claimSet, nil
 }
 
-// Decode returns ClaimSet
+// DecodeIDToken returns the header and claims of a token WITHOUT verifying it, e.g. to choose a verifier
+// by aud or iss, or to log the kid of a rejected token. Never trust the result for authentication.
+func DecodeIDToken(token string) (*jws.Header, *ClaimSet, error) {
+	return parseJWT(token)
+}
+
+// DecodeHeader returns the header of a token WITHOUT verifying it, see DecodeIDToken
+func DecodeHeader(token string) (*jws.Header, error) {
+	return parseHeader(token)
+}
+
+// parseHeader decodes only the header of the token
+func parseHeader(token string) (*jws.Header, error) {
+	decoded, err := decodeSegment(token, 0)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	header := &jws.Header{}
+	if json.Unmarshal(decoded, header) != nil {
+		return nil, ErrInvalidToken
+	}
+	return header, nil
+}
+
+// Decode returns ClaimSet WITHOUT verifying the token, see DecodeIDToken
 func Decode(token string) (*ClaimSet, error) {
 	s := strings.Split(token, ".")
 	if len(s) != 3 {
@@ -47,7 +70,33 @@ func Decode(token string) (*ClaimSet, error) {
 	if err != nil {
 		return nil, err
 	}
-	c := &ClaimSet{}
+	c := &ClaimSet{payload: s[1]}
 	err = json.NewDecoder(bytes.NewBuffer(decoded)).Decode(c)
 	return c, err
 }
+
+func decodePayload(token string) ([]byte, error) {
+	return decodeSegment(token, 1)
+}
+
+func decodeSegment(token string, i int) ([]byte, error) {
+	s := strings.Split(token, ".")
+	if len(s) != 3 {
+		return nil, ErrInvalidToken
+	}
+	return base64.RawURLEncoding.DecodeString(s[i])
+}
+
+func decodeRawClaims(token string) (map[string]interface{}, error) {
+	return decodeRawSegment(token, 1)
+}
+
+func decodeRawSegment(token string, i int) (map[string]interface{}, error) {
+	decoded, err := decodeSegment(token, i)
+	if err != nil {
+		return nil, err
+	}
+	claims := map[string]interface{}{}
+	err = json.NewDecoder(bytes.NewBuffer(decoded)).Decode(&claims)
+	return claims, err
+}
@@ -0,0 +1,73 @@
+package googleIDVerifier
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2/jws"
+)
+
+var decodeBufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 2048)
+		return &b
+	},
+}
+
+// parseJWTFast is parseJWT splitting the token without allocating and decoding its segments into
+// pooled buffers, see CertsVerifier.FastDecode
+func parseJWTFast(token string) (*jws.Header, *ClaimSet, error) {
+	headerSeg, claimsSeg, ok := splitToken(token)
+	if !ok {
+		return nil, nil, ErrInvalidToken
+	}
+	header := &jws.Header{}
+	err := decodeSegmentInto(headerSeg, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	claimSet := &ClaimSet{payload: claimsSeg}
+	err = decodeSegmentInto(claimsSeg, claimSet)
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, claimSet, nil
+}
+
+// splitToken returns the header and claims segments of a token made of exactly three segments
+func splitToken(token string) (string, string, bool) {
+	first := strings.IndexByte(token, '.')
+	if first < 0 {
+		return "", "", false
+	}
+	second := strings.IndexByte(token[first+1:], '.')
+	if second < 0 {
+		return "", "", false
+	}
+	second += first + 1
+	if strings.IndexByte(token[second+1:], '.') >= 0 {
+		return "", "", false
+	}
+	return token[:first], token[first+1 : second], true
+}
+
+func decodeSegmentInto(seg string, v interface{}) error {
+	srcp := decodeBufPool.Get().(*[]byte)
+	dstp := decodeBufPool.Get().(*[]byte)
+	defer decodeBufPool.Put(srcp)
+	defer decodeBufPool.Put(dstp)
+
+	src := append((*srcp)[:0], seg...)
+	dst := *dstp
+	if n := base64.RawURLEncoding.DecodedLen(len(src)); cap(dst) < n {
+		dst = make([]byte, n)
+	}
+	n, err := base64.RawURLEncoding.Decode(dst[:cap(dst)], src)
+	*srcp, *dstp = src, dst
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(dst[:n], v)
+}
@@ -0,0 +1,51 @@
+package googleIDVerifier
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseJWTFast(t *testing.T) {
+	header, claimSet, err := parseJWTFast(validTestToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedHeader, expectedClaimSet, _ := parseJWT(validTestToken)
+	if !reflect.DeepEqual(header, expectedHeader) || !reflect.DeepEqual(claimSet, expectedClaimSet) {
+		t.Error("Expect fast path to decode like the default path")
+	}
+
+	for _, token := range []string{"", "a.b", "a.b.c.d", "..", "e30.!!!.c"} {
+		if _, _, err := parseJWTFast(token); err == nil {
+			t.Errorf("Expect error for %q", token)
+		}
+	}
+}
+
+func TestFastDecodeOption(t *testing.T) {
+	certs, _ := getTestCerts()
+	_, expected, _ := parseJWT(validTestToken)
+	v := NewStaticVerifier(certs, WithFastDecode(), WithClock(func() time.Time { return time.Unix(expected.Exp, 0) }))
+	claimSet, err := v.VerifyIDToken(validTestToken, expected.Aud)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimSet.Email != expected.Email {
+		t.Errorf("Invalid Email: %s", claimSet.Email)
+	}
+}
+
+func BenchmarkParseJWT(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _, _ = parseJWT(validTestToken)
+	}
+}
+
+func BenchmarkParseJWTFast(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _, _ = parseJWTFast(validTestToken)
+	}
+}
@@ -0,0 +1,30 @@
+//go:build go1.18
+// +build go1.18
+
+package googleIDVerifier
+
+import (
+	"reflect"
+	"testing"
+)
+
+func FuzzParseJWTFast(f *testing.F) {
+	f.Add(validTestToken)
+	f.Add(wrongSigToken)
+	f.Add("e30.e30.")
+	f.Add("a.b.c.d")
+	f.Fuzz(func(t *testing.T, token string) {
+		header, claimSet, err := parseJWTFast(token)
+		if err != nil {
+			// the fast path may be stricter, e.g. it rejects trailing data after the JSON segments
+			return
+		}
+		expectedHeader, expectedClaimSet, expectedErr := parseJWT(token)
+		if expectedErr != nil {
+			t.Fatalf("fast path accepted a token rejected by the default path: %v", expectedErr)
+		}
+		if !reflect.DeepEqual(header, expectedHeader) || !reflect.DeepEqual(claimSet, expectedClaimSet) {
+			t.Fatal("decoded token mismatch")
+		}
+	})
+}
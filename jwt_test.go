@@ -0,0 +1,23 @@
+package googleIDVerifier
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecodeIDToken(t *testing.T) {
+	header, claimSet, err := DecodeIDToken(wrongSigToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.KeyID == "" || header.Algorithm != "RS256" || claimSet.Iss == "" || claimSet.Aud == "" {
+		t.Errorf("Unexpected header %+v and claims %+v", header, claimSet)
+	}
+	onlyHeader, err := DecodeHeader(wrongSigToken)
+	if err != nil || onlyHeader.KeyID != header.KeyID {
+		t.Errorf("Expect kid %s, got %+v, %v", header.KeyID, onlyHeader, err)
+	}
+	if _, err = DecodeHeader("garbage"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expect ErrInvalidToken, got %v", err)
+	}
+}
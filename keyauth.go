@@ -0,0 +1,50 @@
+package googleIDVerifier
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+// RequestAuth authenticates a request fetching keys or a discovery document, e.g. for a private issuer
+// protecting its key endpoint. See BearerAuth, BasicAuth and TokenSourceAuth.
+type RequestAuth func(r *http.Request) error
+
+// BearerAuth returns a RequestAuth sending a static bearer token
+func BearerAuth(token string) RequestAuth {
+	return func(r *http.Request) error {
+		r.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+// BasicAuth returns a RequestAuth sending HTTP basic credentials
+func BasicAuth(username, password string) RequestAuth {
+	return func(r *http.Request) error {
+		r.SetBasicAuth(username, password)
+		return nil
+	}
+}
+
+// TokenSourceAuth returns a RequestAuth sending a token of ts, e.g. a service account access token
+// from golang.org/x/oauth2/google, refreshed by ts when it expires
+func TokenSourceAuth(ts oauth2.TokenSource) RequestAuth {
+	return func(r *http.Request) error {
+		token, err := ts.Token()
+		if err != nil {
+			return err
+		}
+		token.SetAuthHeader(r)
+		return nil
+	}
+}
+
+// NewTLSClient returns a client for key endpoints requiring mutual TLS, e.g. an enterprise JWKS mirror,
+// presenting the client certificates of config, with the same 10 seconds timeout as the default client.
+// Use it with WithHTTPClient or URLKeySource.
+func NewTLSClient(config *tls.Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	return &http.Client{Transport: transport, Timeout: defaultHTTPClient.Timeout}
+}
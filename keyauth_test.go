@@ -0,0 +1,108 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestKeyFetchAuth(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issuer string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !(ok && user == "mirror" && password == "secret") &&
+			r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_ = json.NewEncoder(w).Encode(ProviderConfig{Issuer: issuer, JWKSURI: issuer + "/keys"})
+		case "/keys":
+			_ = json.NewEncoder(w).Encode(response{Keys: []*key{encodeECKey("private", &priv.PublicKey)}})
+		}
+	}))
+	defer srv.Close()
+	issuer = srv.URL
+	ctx := context.Background()
+
+	if _, err = NewOIDCVerifier(ctx, issuer); err == nil {
+		t.Error("Expect discovery to fail without credentials")
+	}
+	v, err := NewOIDCVerifier(ctx, issuer, WithKeyFetchAuth(TokenSourceAuth(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "secret"}))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = v.KeySet.RefreshContext(ctx); err != nil || !v.KeySet.Certs().HasKey("private") {
+		t.Errorf("Expect the keys to be fetched with the token, got %v", err)
+	}
+
+	for _, auth := range []RequestAuth{BearerAuth("secret"), BasicAuth("mirror", "secret")} {
+		certs, err := AuthenticatedURLKeySource(issuer+"/keys", nil, auth).Keys(ctx)
+		if err != nil || !certs.HasKey("private") {
+			t.Errorf("Expect the keys to be fetched with credentials, got %v", err)
+		}
+	}
+	if _, err = URLKeySource(issuer+"/keys", nil).Keys(ctx); err == nil {
+		t.Error("Expect the keys not to be served without credentials")
+	}
+}
+
+func TestNewTLSClient(t *testing.T) {
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &clientKey.PublicKey, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCert, _ := x509.ParseCertificate(der)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	certs, _ := getTestCerts()
+	jwks, _ := certs.MarshalJSON()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(jwks)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(srv.Certificate())
+	ctx := context.Background()
+
+	if _, err = URLKeySource(srv.URL, NewTLSClient(&tls.Config{RootCAs: rootCAs})).Keys(ctx); err == nil {
+		t.Error("Expect the endpoint to require a client certificate")
+	}
+	client := NewTLSClient(&tls.Config{RootCAs: rootCAs, Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: clientKey}}})
+	fetched, err := URLKeySource(srv.URL, client).Keys(ctx)
+	if err != nil || len(fetched.KeyIDs()) != len(certs.KeyIDs()) {
+		t.Errorf("Expect the keys to be fetched over mutual TLS, got %v", err)
+	}
+
+	v := NewVerifier(WithClientCertificates(tls.Certificate{Certificate: [][]byte{der}, PrivateKey: clientKey}))
+	if config := v.HTTPClient.Transport.(*http.Transport).TLSClientConfig; len(config.Certificates) != 1 {
+		t.Error("Expect the verifier client to present the certificate")
+	}
+}
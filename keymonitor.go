@@ -0,0 +1,142 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// KeyEventKind is the kind of a KeyEvent
+type KeyEventKind string
+
+const (
+	// KeyAdded is a kid served for the first time
+	KeyAdded KeyEventKind = "key_added"
+	// KeyRemoved is a kid no longer served, after at least MinKeyAge
+	KeyRemoved KeyEventKind = "key_removed"
+	// KeyRemovedEarly is a kid removed before MinKeyAge, which may get valid tokens rejected
+	KeyRemovedEarly KeyEventKind = "key_removed_early"
+	// KeyFetchFailed is a failed fetch or invalid data served by the key endpoint
+	KeyFetchFailed KeyEventKind = "fetch_failed"
+)
+
+// KeyEvent is a change observed by a KeyMonitor
+type KeyEvent struct {
+	Kind  KeyEventKind `json:"kind"`
+	Kid   string       `json:"kid,omitempty"`
+	Time  time.Time    `json:"time"`
+	Error string       `json:"error,omitempty"`
+}
+
+// Alert reports whether the event needs an operator's attention
+func (e KeyEvent) Alert() bool {
+	return e.Kind == KeyRemovedEarly || e.Kind == KeyFetchFailed
+}
+
+var errNoKeys = errors.New("no keys served")
+
+// KeyMonitor polls a key source and records key rotations, to warn before verifiers start rejecting tokens
+type KeyMonitor struct {
+	Source KeySource
+	// MinKeyAge is how long a key is expected to be served, shorter lived keys raise KeyRemovedEarly
+	MinKeyAge time.Duration
+	// OnEvent, if set, is called for every event
+	OnEvent func(event KeyEvent)
+
+	mu        sync.Mutex
+	polled    bool
+	firstSeen map[string]time.Time
+	certs     *Certs
+	events    []KeyEvent
+}
+
+// NewKeyMonitor returns a KeyMonitor for the given source, expecting keys to live at least a day
+func NewKeyMonitor(source KeySource) *KeyMonitor {
+	return &KeyMonitor{Source: source, MinKeyAge: 24 * time.Hour}
+}
+
+// Poll fetches the keys once and returns the new events. Keys present on the first poll
+// have an unknown age, so their removal is never reported as early.
+func (m *KeyMonitor) Poll() []KeyEvent {
+	return m.poll(context.Background())
+}
+
+func (m *KeyMonitor) poll(ctx context.Context) []KeyEvent {
+	certs, err := m.Source.Keys(ctx)
+	if err == nil && (certs == nil || len(certs.KeyIDs()) == 0) {
+		err = errNoKeys
+	}
+	now := time.Now()
+
+	m.mu.Lock()
+	var events []KeyEvent
+	if err != nil {
+		events = append(events, KeyEvent{Kind: KeyFetchFailed, Time: now, Error: err.Error()})
+	} else {
+		if m.firstSeen == nil {
+			m.firstSeen = map[string]time.Time{}
+		}
+		for _, kid := range certs.KeyIDs() {
+			if _, ok := m.firstSeen[kid]; ok {
+				continue
+			}
+			seen := now
+			if !m.polled {
+				seen = time.Time{}
+			}
+			m.firstSeen[kid] = seen
+			events = append(events, KeyEvent{Kind: KeyAdded, Kid: kid, Time: now})
+		}
+		for kid, seen := range m.firstSeen {
+			if certs.HasKey(kid) {
+				continue
+			}
+			kind := KeyRemoved
+			if !seen.IsZero() && now.Sub(seen) < m.MinKeyAge {
+				kind = KeyRemovedEarly
+			}
+			delete(m.firstSeen, kid)
+			events = append(events, KeyEvent{Kind: kind, Kid: kid, Time: now})
+		}
+		m.certs = certs
+		m.polled = true
+	}
+	m.events = append(m.events, events...)
+	m.mu.Unlock()
+
+	if m.OnEvent != nil {
+		for _, e := range events {
+			m.OnEvent(e)
+		}
+	}
+	return events
+}
+
+// Run polls every interval until ctx is done
+func (m *KeyMonitor) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.poll(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Events returns every event recorded so far
+func (m *KeyMonitor) Events() []KeyEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]KeyEvent(nil), m.events...)
+}
+
+// Certs returns the last keys fetched successfully, e.g. to export them as a snapshot with Certs.MarshalJSON
+func (m *KeyMonitor) Certs() *Certs {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.certs
+}
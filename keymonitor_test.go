@@ -0,0 +1,52 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"testing"
+)
+
+func TestKeyMonitor(t *testing.T) {
+	all, _ := getTestCerts()
+	var kids []string
+	for kid := range all.Keys {
+		kids = append(kids, kid)
+	}
+	if len(kids) < 2 {
+		t.Fatal("Expect at least two test keys")
+	}
+	served := &Certs{Keys: map[string]*rsa.PublicKey{kids[0]: all.Keys[kids[0]]}}
+	var fetchErr error
+	m := NewKeyMonitor(KeySourceFunc(func(context.Context) (*Certs, error) { return served, fetchErr }))
+	alerts := 0
+	m.OnEvent = func(e KeyEvent) {
+		if e.Alert() {
+			alerts++
+		}
+	}
+
+	if events := m.Poll(); len(events) != 1 || events[0].Kind != KeyAdded {
+		t.Errorf("Expect initial key to be added, got %v", events)
+	}
+
+	served = &Certs{Keys: map[string]*rsa.PublicKey{kids[1]: all.Keys[kids[1]]}}
+	events := m.Poll()
+	if len(events) != 2 || events[0].Kind != KeyAdded || events[1].Kind != KeyRemoved || events[1].Kid != kids[0] {
+		t.Errorf("Expect rotation of a key of unknown age, got %v", events)
+	}
+
+	served = &Certs{Keys: map[string]*rsa.PublicKey{kids[0]: all.Keys[kids[0]]}}
+	events = m.Poll()
+	if len(events) != 2 || events[1].Kind != KeyRemovedEarly || events[1].Kid != kids[1] {
+		t.Errorf("Expect early removal, got %v", events)
+	}
+
+	fetchErr = errors.New("bad gateway")
+	if events := m.Poll(); len(events) != 1 || events[0].Kind != KeyFetchFailed {
+		t.Errorf("Expect fetch failure, got %v", events)
+	}
+	if alerts != 2 || len(m.Events()) != 6 || m.Certs().Keys[kids[0]] == nil {
+		t.Errorf("Unexpected monitor state: %d alerts, events %v", alerts, m.Events())
+	}
+}
@@ -0,0 +1,392 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2/jws"
+)
+
+// keyMissRefreshInterval limits how often an unknown kid may trigger a refetch,
+// so tokens with bogus kids cannot make us hammer the key endpoint
+var keyMissRefreshInterval = time.Minute
+
+var (
+	// refreshAhead is how long before their expiry StartRefresh refetches the keys
+	refreshAhead = 5 * time.Minute
+	// refreshMinInterval is the minimum time between two background refreshes which succeeded
+	refreshMinInterval = time.Minute
+	// refreshMinBackoff and refreshMaxBackoff bound the wait before retrying a failed background refresh
+	refreshMinBackoff = time.Second
+	refreshMaxBackoff = 5 * time.Minute
+)
+
+// KeySet is a set of public keys used to verify token signatures, refreshed from its source when expired
+type KeySet struct {
+	// OnKeyMiss, if set, is called when a token references a kid missing from the key set,
+	// with found telling whether the kid was present after refetching the keys
+	OnKeyMiss func(kid string, found bool)
+	// Metrics, if set, receives the key fetch counts and latencies and the key lookups, see MetricKeyFetches
+	Metrics Metrics
+	// Logger, if set, logs the key fetches and when stale or missing keys are served
+	Logger Logger
+
+	mu        sync.RWMutex
+	certs     *Certs
+	fetch     func(ctx context.Context) (*Certs, error)
+	lastFetch time.Time
+	inFlight  *keyRefresh
+	// lastSuccess is the time of the last successful fetch
+	lastSuccess time.Time
+	// background is set while StartRefresh keeps the keys fresh
+	background bool
+}
+
+// keyRefresh is a fetch shared by the concurrent refreshes of a KeySet
+type keyRefresh struct {
+	done chan struct{}
+	err  error
+}
+
+// NewKeySet returns a KeySet holding the given certs. fetch is used to refresh them
+// and may be nil for a static key set.
+func NewKeySet(certs *Certs, fetch func() (*Certs, error)) *KeySet {
+	if fetch == nil {
+		return NewKeySetContext(certs, nil)
+	}
+	return NewKeySetContext(certs, func(context.Context) (*Certs, error) {
+		return fetch()
+	})
+}
+
+// NewKeySetContext is NewKeySet with a fetch function honoring the context of the verification
+// which triggered it, so callers can bound the time spent waiting for the key endpoint
+func NewKeySetContext(certs *Certs, fetch func(ctx context.Context) (*Certs, error)) *KeySet {
+	return &KeySet{certs: certs, fetch: fetch}
+}
+
+// NewKeySetFromSource returns a KeySet fetching its keys from source on first use
+func NewKeySetFromSource(source KeySource) *KeySet {
+	return NewKeySetContext(nil, source.Keys)
+}
+
+// NewGoogleKeySet returns a KeySet fetching the Google federated sign-on certs on first use
+func NewGoogleKeySet() *KeySet {
+	return NewKeySetContext(nil, fetchGoogleCertsContext)
+}
+
+// NewGoogleKeySetWithClient is NewGoogleKeySet fetching the certs with the given client,
+// e.g. one going through a corporate proxy or with an instrumented transport
+func NewGoogleKeySetWithClient(client *http.Client) *KeySet {
+	return NewKeySetContext(nil, func(ctx context.Context) (*Certs, error) {
+		return fetchCerts(ctx, client, googleOAuth2FederatedSignOnCertsURL)
+	})
+}
+
+// Certs returns the keys currently held, which may be nil or expired
+func (k *KeySet) Certs() *Certs {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.certs
+}
+
+// KeyIDs returns the sorted kids of the keys currently held
+func (k *KeySet) KeyIDs() []string {
+	return k.Certs().KeyIDs()
+}
+
+// LastRefresh returns the time of the last successful fetch, zero if none
+func (k *KeySet) LastRefresh() time.Time {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.lastSuccess
+}
+
+// SetCerts replaces the keys held, e.g. to rotate pinned keys out-of-band
+func (k *KeySet) SetCerts(certs *Certs) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.certs = certs
+}
+
+// Contains reports whether the key set currently holds a key with the given kid
+func (k *KeySet) Contains(kid string) bool {
+	return k.Certs().HasKey(kid)
+}
+
+// GoogleKeySet returns the key set of the Google federated sign-on certs shared by the verifiers
+// without KeySet nor HTTPClient, e.g. to set its Metrics at startup
+func GoogleKeySet() *KeySet {
+	return googleKeySet
+}
+
+// Refresh fetches the keys again from the source of the key set
+func (k *KeySet) Refresh() error {
+	return k.RefreshContext(context.Background())
+}
+
+// RefreshContext is Refresh, giving up when ctx is done. Concurrent refreshes share a single fetch,
+// so a burst of verifications with expired keys does not stampede the key endpoint.
+func (k *KeySet) RefreshContext(ctx context.Context) error {
+	if k.fetch == nil {
+		return nil
+	}
+	for {
+		k.mu.Lock()
+		r := k.inFlight
+		if r == nil {
+			r = &keyRefresh{done: make(chan struct{})}
+			k.inFlight = r
+			k.lastFetch = time.Now()
+			k.mu.Unlock()
+			k.runRefresh(ctx, r)
+			return r.err
+		}
+		k.mu.Unlock()
+
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		// the shared fetch was cancelled by its caller but ours is still wanted, so try again
+		if (errors.Is(r.err, context.Canceled) || errors.Is(r.err, context.DeadlineExceeded)) && ctx.Err() == nil {
+			continue
+		}
+		return r.err
+	}
+}
+
+func (k *KeySet) runRefresh(ctx context.Context, r *keyRefresh) {
+	start := time.Now()
+	certs, err := k.fetch(ctx)
+	if k.Metrics != nil {
+		labels := map[string]string{"outcome": fetchOutcome(err)}
+		k.Metrics.IncCounter(MetricKeyFetches, labels)
+		k.Metrics.ObserveHistogram(MetricKeyFetchDuration, time.Since(start).Seconds(), labels)
+	}
+	if k.Logger != nil {
+		if err != nil {
+			k.Logger.Warn("key fetch failed", "error", err.Error(), "duration", time.Since(start).String())
+		} else {
+			k.Logger.Info("keys fetched", "kids", certs.KeyIDs(), "expiry", certs.Expiry, "duration", time.Since(start).String())
+		}
+	}
+	k.mu.Lock()
+	if err == nil {
+		k.certs = certs
+		k.lastSuccess = time.Now()
+	}
+	r.err = err
+	k.inFlight = nil
+	k.mu.Unlock()
+	close(r.done)
+}
+
+// Verify checks the times, issuer and audience of the token and its signature against the key set
+func (k *KeySet) Verify(token string, allowedAuds []string, issuers []string, maxExpiry time.Duration) (*ClaimSet, error) {
+	return k.verify(context.Background(), token, defaultClaimChecks(allowedAuds, issuers, maxExpiry), k.OnKeyMiss)
+}
+
+// VerifyContext is Verify, giving up on fetching the keys when ctx is done
+func (k *KeySet) VerifyContext(ctx context.Context, token string, allowedAuds []string, issuers []string,
+	maxExpiry time.Duration) (*ClaimSet, error) {
+	return k.verify(ctx, token, defaultClaimChecks(allowedAuds, issuers, maxExpiry), k.OnKeyMiss)
+}
+
+// verify returns the claims of valid tokens, and of expired tokens along with ErrTokenUsedTooLate
+// when checks.allowExpired is set
+func (k *KeySet) verify(ctx context.Context, token string, checks claimChecks,
+	onKeyMiss func(string, bool)) (*ClaimSet, error) {
+	certs, err := k.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parse := parseJWT
+	if checks.fastDecode {
+		parse = parseJWTFast
+	}
+	header, claimSet, err := parse(token)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkAlgorithm(header, checks.algorithms)
+	if err != nil {
+		return nil, err
+	}
+
+	if checks.signatureFirst {
+		err = k.checkSignature(ctx, token, certs, header, onKeyMiss)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var expired error
+	err = checkClaims(claimSet, checks)
+	if err == ErrTokenUsedTooLate && checks.allowExpired {
+		expired, err = err, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !checks.signatureFirst {
+		err = k.checkSignature(ctx, token, certs, header, onKeyMiss)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return claimSet, expired
+}
+
+// VerifySignatureOnly checks only the signature of the token against the key set and returns its claims.
+// Times, issuer and audience are NOT checked: this is meant for forensic tooling analyzing
+// old tokens, never for authenticating requests.
+func (k *KeySet) VerifySignatureOnly(token string) (*ClaimSet, error) {
+	ctx := context.Background()
+	certs, err := k.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	header, claimSet, err := parseJWT(token)
+	if err != nil {
+		return nil, err
+	}
+
+	err = k.checkSignature(ctx, token, certs, header, k.OnKeyMiss)
+	if err != nil {
+		return nil, err
+	}
+
+	return claimSet, nil
+}
+
+// signed reports whether the token is signed by one of the current keys, without refetching them
+func (k *KeySet) signed(ctx context.Context, token string) bool {
+	certs, err := k.current(ctx)
+	if err != nil {
+		return false
+	}
+	header, err := parseHeader(token)
+	if err != nil {
+		return false
+	}
+	return checkSignature(token, certs, header) == nil
+}
+
+// checkSignature verifies the token signature, refetching the keys once when its kid is unknown
+func (k *KeySet) checkSignature(ctx context.Context, token string, certs *Certs, header *jws.Header,
+	onKeyMiss func(string, bool)) error {
+	if !certs.HasKey(header.KeyID) {
+		k.observeLookup("unknown_kid")
+		if k.Logger != nil {
+			k.Logger.Info("unknown kid, refreshing keys", "kid", header.KeyID)
+		}
+		certs = k.refreshOnKeyMiss(ctx, certs)
+		if onKeyMiss != nil {
+			onKeyMiss(header.KeyID, certs.HasKey(header.KeyID))
+		}
+	}
+	return checkSignature(token, certs, header)
+}
+
+func (k *KeySet) refreshOnKeyMiss(ctx context.Context, certs *Certs) *Certs {
+	k.mu.RLock()
+	recent := time.Since(k.lastFetch) < keyMissRefreshInterval
+	k.mu.RUnlock()
+	if k.fetch == nil || recent {
+		return certs
+	}
+	if k.RefreshContext(ctx) != nil {
+		return certs
+	}
+	return k.Certs()
+}
+
+// StartRefresh keeps the keys fresh in the background until ctx is done: they are refetched ahead of
+// their expiry, and after a failure with an exponential backoff while the last good keys keep being
+// served, even expired. Verifications then never wait for the key endpoint once a fetch succeeded.
+// The error of the first fetch is returned, the background refresh retrying it in any case.
+func (k *KeySet) StartRefresh(ctx context.Context) error {
+	if k.fetch == nil {
+		return nil
+	}
+	k.mu.Lock()
+	k.background = true
+	k.mu.Unlock()
+	err := k.RefreshContext(ctx)
+	go k.refreshLoop(ctx, err)
+	return err
+}
+
+func (k *KeySet) refreshLoop(ctx context.Context, err error) {
+	defer func() {
+		k.mu.Lock()
+		k.background = false
+		k.mu.Unlock()
+	}()
+	ahead, minInterval, minBackoff, maxBackoff := refreshAhead, refreshMinInterval, refreshMinBackoff, refreshMaxBackoff
+	backoff := minBackoff
+	for {
+		var wait time.Duration
+		if err != nil {
+			wait = backoff
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		} else {
+			backoff = minBackoff
+			wait = time.Until(k.Certs().Expiry.Add(-ahead))
+			if wait < minInterval {
+				wait = minInterval
+			}
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		err = k.RefreshContext(ctx)
+	}
+}
+
+// current returns the held certs, refreshing them first when missing or expired,
+// unless StartRefresh is refreshing them in the background
+func (k *KeySet) current(ctx context.Context) (*Certs, error) {
+	k.mu.RLock()
+	certs, background := k.certs, k.background
+	k.mu.RUnlock()
+	if k.fetch == nil || (certs != nil && (background || time.Now().Before(certs.Expiry))) {
+		k.observeLookup("hit")
+		if k.Logger != nil && certs != nil && !time.Now().Before(certs.Expiry) {
+			k.Logger.Debug("serving expired keys", "expiry", certs.Expiry, "background", background)
+		}
+		return certs, nil
+	}
+	k.observeLookup("miss")
+	if k.Logger != nil {
+		k.Logger.Debug("keys missing or expired, fetching")
+	}
+	err := k.RefreshContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return k.Certs(), nil
+}
+
+func (k *KeySet) observeLookup(result string) {
+	if k.Metrics != nil {
+		k.Metrics.IncCounter(MetricKeyLookups, map[string]string{"result": result})
+	}
+}
@@ -0,0 +1,165 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKeySet(t *testing.T) {
+	fetches := 0
+	keySet := NewKeySet(nil, func() (*Certs, error) {
+		fetches++
+		return getTestCerts()
+	})
+
+	if keySet.Contains("3f3ef9c7803cd0b8d75247ee0d31fdd5c2cf3812") {
+		t.Error("Expect empty key set before first use")
+	}
+
+	nowFn = func() time.Time {
+		return time.Unix(1525722119, 0)
+	}
+	defer func() { nowFn = time.Now }()
+
+	_, err := keySet.Verify(validTestToken, []string{"407408718192.apps.googleusercontent.com"}, Issuers, MaxTokenLifetime)
+	if err != nil {
+		t.Error(err)
+	}
+	if !keySet.Contains("3f3ef9c7803cd0b8d75247ee0d31fdd5c2cf3812") {
+		t.Error("Expect key set to contain the token kid after fetching")
+	}
+
+	_, _ = keySet.Verify(validTestToken, nil, Issuers, MaxTokenLifetime)
+	if fetches != 1 {
+		t.Errorf("Expect certs to be fetched once, got %d", fetches)
+	}
+
+	err = keySet.Refresh()
+	if err != nil || fetches != 2 {
+		t.Errorf("Expect Refresh to fetch again, got %d fetches and %v", fetches, err)
+	}
+}
+
+func TestKeySetKeyMiss(t *testing.T) {
+	_, claimSet, _ := parseJWT(validTestToken)
+	nowFn = func() time.Time {
+		return time.Unix(claimSet.Exp, 0)
+	}
+	defer func() { nowFn = time.Now }()
+
+	fetches := 0
+	keySet := NewKeySet(&Certs{Expiry: time.Now().Add(time.Hour)}, func() (*Certs, error) {
+		fetches++
+		return getTestCerts()
+	})
+	var misses []bool
+	keySet.OnKeyMiss = func(kid string, found bool) {
+		misses = append(misses, found)
+	}
+
+	_, err := keySet.Verify(validTestToken, []string{claimSet.Aud}, Issuers, MaxTokenLifetime)
+	if err != nil {
+		t.Error(err)
+	}
+	if fetches != 1 || len(misses) != 1 || !misses[0] {
+		t.Errorf("Expect one refetch finding the kid, got %d fetches and %v", fetches, misses)
+	}
+
+	bogus := "eyJhbGciOiJSUzI1NiIsImtpZCI6ImJvZ3VzIn0" + validTestToken[strings.Index(validTestToken, "."):]
+	_, err = keySet.Verify(bogus, []string{claimSet.Aud}, Issuers, MaxTokenLifetime)
+	if err != ErrPublicKeyNotFound {
+		t.Errorf("Expect ErrPublicKeyNotFound, got %v", err)
+	}
+	if fetches != 1 || len(misses) != 2 || misses[1] {
+		t.Errorf("Expect a rate limited miss without refetch, got %d fetches and %v", fetches, misses)
+	}
+}
+
+func TestKeySetSingleFlight(t *testing.T) {
+	var fetches int32
+	release := make(chan struct{})
+	keySet := NewKeySet(nil, func() (*Certs, error) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		return getTestCerts()
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := keySet.Refresh(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if fetches != 1 || keySet.Certs() == nil {
+		t.Errorf("Expect concurrent refreshes to share one fetch, got %d", fetches)
+	}
+}
+
+func TestCacheMaxAge(t *testing.T) {
+	date := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		header http.Header
+		age    int64
+	}{
+		{http.Header{"Cache-Control": {"public, max-age=19702"}}, 19702},
+		{http.Header{"Date": {date.Format(http.TimeFormat)}, "Expires": {date.Add(time.Hour).Format(http.TimeFormat)}}, 3600},
+		{http.Header{"Date": {date.Format(http.TimeFormat)}, "Expires": {date.Add(-time.Hour).Format(http.TimeFormat)}}, 0},
+		{http.Header{}, defaultCacheAge},
+	} {
+		age, err := cacheMaxAge(test.header)
+		if err != nil || age != test.age {
+			t.Errorf("Expect %d for %v, got %d %v", test.age, test.header, age, err)
+		}
+	}
+}
+
+func TestKeySetStartRefresh(t *testing.T) {
+	defer func(ahead, interval, backoff time.Duration) {
+		refreshAhead, refreshMinInterval, refreshMinBackoff = ahead, interval, backoff
+	}(refreshAhead, refreshMinInterval, refreshMinBackoff)
+	refreshAhead, refreshMinInterval, refreshMinBackoff = 0, 5*time.Millisecond, 5*time.Millisecond
+
+	var fetches int32
+	keySet := NewKeySet(nil, func() (*Certs, error) {
+		if atomic.AddInt32(&fetches, 1) > 1 {
+			return nil, errors.New("unavailable")
+		}
+		certs, err := getTestCerts()
+		certs.Expiry = time.Now()
+		return certs, err
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := keySet.StartRefresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	refreshed := keySet.LastRefresh()
+	if refreshed.IsZero() || len(keySet.KeyIDs()) == 0 {
+		t.Fatal("Expect keys after the first refresh")
+	}
+	for atomic.LoadInt32(&fetches) < 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	certs, err := keySet.current(ctx)
+	if err != nil || certs == nil {
+		t.Errorf("Expect expired keys to be served while refreshes fail, got %v", err)
+	}
+	if keySet.LastRefresh() != refreshed {
+		t.Error("Expect LastRefresh to ignore failed refreshes")
+	}
+}
@@ -0,0 +1,175 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// KeySource provides the keys of a KeySet, see NewKeySetFromSource. Implement it to fetch keys
+// from a custom location, e.g. a secret store or a mirror of the Google endpoint.
+type KeySource interface {
+	Keys(ctx context.Context) (*Certs, error)
+}
+
+// KeySourceFunc adapts a function to KeySource
+type KeySourceFunc func(ctx context.Context) (*Certs, error)
+
+// Keys calls f
+func (f KeySourceFunc) Keys(ctx context.Context) (*Certs, error) {
+	return f(ctx)
+}
+
+// SourceHealth is the state of a source in a KeySourceChain
+type SourceHealth struct {
+	Name string
+	// Healthy is false when the last fetch from the source failed
+	Healthy     bool
+	LastError   error
+	LastSuccess time.Time
+	// Failures counts consecutive failed fetches
+	Failures int
+}
+
+// KeySourceChain fetches keys from several sources in priority order, falling back to the next
+// source when one fails. It is itself a KeySource, see NewKeySetFromSource.
+type KeySourceChain struct {
+	mu      sync.Mutex
+	sources []chainedSource
+}
+
+type chainedSource struct {
+	source KeySource
+	health SourceHealth
+}
+
+// NewKeySourceChain returns an empty chain
+func NewKeySourceChain() *KeySourceChain {
+	return &KeySourceChain{}
+}
+
+// Add appends a source with a lower priority than the ones already added
+func (c *KeySourceChain) Add(name string, source KeySource) *KeySourceChain {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sources = append(c.sources, chainedSource{source: source, health: SourceHealth{Name: name, Healthy: true}})
+	return c
+}
+
+// Keys returns the keys of the first source that succeeds, or the error of the last one. The sources
+// are fetched without holding the chain, so Health does not wait for a slow source.
+func (c *KeySourceChain) Keys(ctx context.Context) (*Certs, error) {
+	c.mu.Lock()
+	sources := make([]KeySource, len(c.sources))
+	for i, s := range c.sources {
+		sources[i] = s.source
+	}
+	c.mu.Unlock()
+
+	err := ErrNoKeySource
+	for i, source := range sources {
+		var certs *Certs
+		certs, err = source.Keys(ctx)
+		c.mu.Lock()
+		health := &c.sources[i].health
+		if err != nil {
+			health.Healthy = false
+			health.LastError = err
+			health.Failures++
+		} else {
+			health.Healthy = true
+			health.LastError = nil
+			health.LastSuccess = time.Now()
+			health.Failures = 0
+		}
+		c.mu.Unlock()
+		if err == nil {
+			return certs, nil
+		}
+	}
+	return nil, err
+}
+
+// Health returns the state of every source, in priority order
+func (c *KeySourceChain) Health() []SourceHealth {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	health := make([]SourceHealth, 0, len(c.sources))
+	for _, s := range c.sources {
+		health = append(health, s.health)
+	}
+	return health
+}
+
+// MergedKeySource returns a source fetching every given source and trusting the union of their keys,
+// e.g. the Google PEM and JWKS endpoints plus a pinned file during a migration. It fails when
+// any source fails or when two sources disagree on the key of a kid.
+func MergedKeySource(sources ...KeySource) KeySource {
+	return KeySourceFunc(func(ctx context.Context) (*Certs, error) {
+		if len(sources) == 0 {
+			return nil, ErrNoKeySource
+		}
+		merged, err := sources[0].Keys(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, source := range sources[1:] {
+			certs, err := source.Keys(ctx)
+			if err != nil {
+				return nil, err
+			}
+			merged, err = MergeCerts(merged, certs)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return merged, nil
+	})
+}
+
+// StaticKeySource returns a source always serving the given pinned keys
+func StaticKeySource(certs *Certs) KeySource {
+	return KeySourceFunc(func(context.Context) (*Certs, error) {
+		return certs, nil
+	})
+}
+
+// FileKeySource returns a source reading keys from a local file, see LoadCertsFile
+func FileKeySource(path string) KeySource {
+	return KeySourceFunc(func(context.Context) (*Certs, error) {
+		return LoadCertsFile(path)
+	})
+}
+
+// LoadCertsFile reads keys in JWKS or PEM certs format from a local file
+func LoadCertsFile(path string) (*Certs, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCerts(data)
+}
+
+// URLKeySource returns a source fetching keys in JWKS or PEM certs format from url with client,
+// or a client with a 10 seconds timeout when nil, e.g. for a private mirror of the Google certs.
+// The keys expire according to the Cache-Control or Expires headers of the response.
+func URLKeySource(url string, client *http.Client) KeySource {
+	return KeySourceFunc(func(ctx context.Context) (*Certs, error) {
+		return fetchCerts(ctx, client, url)
+	})
+}
+
+// AuthenticatedURLKeySource is URLKeySource authenticating the requests with auth, for a private
+// key endpoint
+func AuthenticatedURLKeySource(url string, client *http.Client, auth RequestAuth) KeySource {
+	return KeySourceFunc(func(ctx context.Context) (*Certs, error) {
+		return fetchCertsWithAuth(ctx, client, auth, url)
+	})
+}
+
+// GoogleKeySource returns a source fetching the Google federated sign-on certs
+func GoogleKeySource() KeySource {
+	return KeySourceFunc(fetchGoogleCertsContext)
+}
@@ -0,0 +1,139 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestKeySourceChain(t *testing.T) {
+	errDown := errors.New("down")
+	pinned, _ := getTestCerts()
+	chain := NewKeySourceChain().
+		Add("remote", KeySourceFunc(func(context.Context) (*Certs, error) { return nil, errDown })).
+		Add("file", FileKeySource("google-keys.json")).
+		Add("pinned", StaticKeySource(pinned))
+
+	certs, err := chain.Keys(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if certs.Keys["bc49530e1ff9083dd5eeaa06be2ce437f49c905e"] == nil {
+		t.Error("Expect keys from the file source")
+	}
+
+	health := chain.Health()
+	if health[0].Healthy || health[0].LastError != errDown || health[0].Failures != 1 {
+		t.Errorf("Expect remote source to be unhealthy, got %+v", health[0])
+	}
+	if !health[1].Healthy || health[1].LastSuccess.IsZero() {
+		t.Errorf("Expect file source to be healthy, got %+v", health[1])
+	}
+
+	_, err = NewKeySourceChain().Keys(context.Background())
+	if err != ErrNoKeySource {
+		t.Errorf("Expect ErrNoKeySource, got %v", err)
+	}
+}
+
+func TestMergedKeySource(t *testing.T) {
+	pinned, _ := getTestCerts()
+	merged, err := MergedKeySource(StaticKeySource(pinned), FileKeySource("google-keys.json"), StaticKeySource(pinned)).Keys(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kid := range []string{"3f3ef9c7803cd0b8d75247ee0d31fdd5c2cf3812", "bc49530e1ff9083dd5eeaa06be2ce437f49c905e"} {
+		if merged.Keys[kid] == nil {
+			t.Errorf("Expect merged keys to contain %s", kid)
+		}
+	}
+
+	file, _ := FileKeySource("google-keys.json").Keys(context.Background())
+	conflicting := &Certs{Keys: map[string]*rsa.PublicKey{
+		"3f3ef9c7803cd0b8d75247ee0d31fdd5c2cf3812": file.Keys["bc49530e1ff9083dd5eeaa06be2ce437f49c905e"],
+	}}
+	_, err = MergeCerts(pinned, conflicting)
+	if !errors.Is(err, ErrKeyConflict) {
+		t.Errorf("Expect ErrKeyConflict, got %v", err)
+	}
+	ec, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, err = MergeCerts(pinned, &Certs{ECKeys: map[string]*ecdsa.PublicKey{"3f3ef9c7803cd0b8d75247ee0d31fdd5c2cf3812": &ec.PublicKey}})
+	if !errors.Is(err, ErrKeyConflict) {
+		t.Errorf("Expect ErrKeyConflict for a kid with an RSA and an EC key, got %v", err)
+	}
+
+	for _, pair := range [][2]*Certs{{nil, pinned}, {pinned, nil}} {
+		merged, err = MergeCerts(pair[0], pair[1])
+		if err != nil || len(merged.Keys) != len(pinned.Keys) || !merged.Expiry.Equal(pinned.Expiry) {
+			t.Errorf("Expect a nil set to be empty, got %v %v", merged, err)
+		}
+	}
+	if merged, err = MergeCerts(nil, nil); err != nil || len(merged.KeyIDs()) != 0 {
+		t.Errorf("Expect no keys, got %v %v", merged, err)
+	}
+}
+
+func TestKeySource(t *testing.T) {
+	certs, _ := getTestCerts()
+	jwks, _ := certs.MarshalJSON()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(jwks)
+	}))
+	defer srv.Close()
+	_, claimSet, _ := parseJWT(validTestToken)
+	nowFn = func() time.Time { return time.Unix(claimSet.Exp, 0) }
+	defer func() { nowFn = time.Now }()
+
+	var fetches int
+	counting := KeySourceFunc(func(ctx context.Context) (*Certs, error) {
+		fetches++
+		return URLKeySource(srv.URL, nil).Keys(ctx)
+	})
+	for _, v := range []*CertsVerifier{NewVerifier(WithKeySource(counting)), NewVerifier(WithCertsURL(srv.URL))} {
+		if _, err := v.VerifyIDToken(validTestToken, claimSet.Aud); err != nil {
+			t.Error(err)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("Expect the custom source to be used once, got %d", fetches)
+	}
+
+	pinned := NewVerifier(WithCertsURL(srv.URL), WithPinnedKeyIDs("another-kid"))
+	if _, err := pinned.VerifyIDToken(validTestToken, claimSet.Aud); !errors.Is(err, ErrPublicKeyNotFound) {
+		t.Errorf("Expect ErrPublicKeyNotFound for an unpinned kid, got %v", err)
+	}
+	pinned.PinnedKeyIDs = append(pinned.PinnedKeyIDs, "3f3ef9c7803cd0b8d75247ee0d31fdd5c2cf3812")
+	if _, err := pinned.VerifyIDToken(validTestToken, claimSet.Aud); err != nil {
+		t.Errorf("Expect a pinned kid to be accepted, got %v", err)
+	}
+}
+
+func TestKeySourceChainHealthDuringFetch(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	chain := NewKeySourceChain().Add("slow", KeySourceFunc(func(context.Context) (*Certs, error) {
+		close(started)
+		<-release
+		return nil, errors.New("down")
+	}))
+	go func() { _, _ = chain.Keys(context.Background()) }()
+	<-started
+
+	health := make(chan []SourceHealth)
+	go func() { health <- chain.Health() }()
+	select {
+	case h := <-health:
+		if !h[0].Healthy {
+			t.Errorf("Expect the source to be healthy until its fetch fails, got %+v", h[0])
+		}
+	case <-time.After(time.Second):
+		t.Error("Expect Health not to wait for a fetch")
+	}
+	close(release)
+}
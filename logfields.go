@@ -0,0 +1,76 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// LogFields are identity fields derived from a verified token which are safe to put in access logs
+type LogFields struct {
+	// SubjectHash is a hash of the sub claim, stable for a given hash key
+	SubjectHash  string
+	Audience     string
+	HostedDomain string
+	KeyID        string
+}
+
+type logFieldsKey struct{}
+
+// NewLogFields derives LogFields from an already verified token.
+// When hashKey is set the subject is hashed with HMAC-SHA256, otherwise with plain SHA-256.
+// The audiences of tokens with several are comma separated.
+func NewLogFields(token string, hashKey []byte) (*LogFields, error) {
+	header, claimSet, err := parseJWT(token)
+	if err != nil {
+		return nil, err
+	}
+	return &LogFields{
+		SubjectHash:  hashSubject(claimSet.Sub, hashKey),
+		Audience:     strings.Join(claimSet.audiences(), ","),
+		HostedDomain: claimSet.HostedDomain,
+		KeyID:        header.KeyID,
+	}, nil
+}
+
+// Map returns the fields keyed by name, omitting empty ones
+func (f *LogFields) Map() map[string]string {
+	m := map[string]string{}
+	for k, v := range map[string]string{
+		"sub_hash": f.SubjectHash,
+		"aud":      f.Audience,
+		"hd":       f.HostedDomain,
+		"kid":      f.KeyID,
+	} {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// ContextWithLogFields returns a copy of ctx carrying the given fields
+func ContextWithLogFields(ctx context.Context, f *LogFields) context.Context {
+	return context.WithValue(ctx, logFieldsKey{}, f)
+}
+
+// LogFieldsFromContext returns the fields stored by ContextWithLogFields, if any
+func LogFieldsFromContext(ctx context.Context) (*LogFields, bool) {
+	f, ok := ctx.Value(logFieldsKey{}).(*LogFields)
+	return f, ok
+}
+
+func hashSubject(sub string, hashKey []byte) string {
+	if sub == "" {
+		return ""
+	}
+	if len(hashKey) > 0 {
+		mac := hmac.New(sha256.New, hashKey)
+		mac.Write([]byte(sub))
+		return hex.EncodeToString(mac.Sum(nil)[:16])
+	}
+	sum := sha256.Sum256([]byte(sub))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,34 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestLogFields(t *testing.T) {
+	fields, err := NewLogFields(validTestToken, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := fields.Map()
+	if m["kid"] != "3f3ef9c7803cd0b8d75247ee0d31fdd5c2cf3812" {
+		t.Errorf("Invalid kid: %s", m["kid"])
+	}
+	if len(m["sub_hash"]) != 32 || strings.Contains(validTestToken, m["sub_hash"]) {
+		t.Errorf("Invalid sub_hash: %s", m["sub_hash"])
+	}
+	if _, ok := m["hd"]; ok {
+		t.Error("Expect empty hd to be omitted")
+	}
+
+	unkeyed, _ := NewLogFields(validTestToken, nil)
+	if unkeyed.SubjectHash == fields.SubjectHash {
+		t.Error("Expect hash key to change the subject hash")
+	}
+
+	ctx := ContextWithLogFields(context.Background(), fields)
+	if f, ok := LogFieldsFromContext(ctx); !ok || f != fields {
+		t.Error("Expect fields from context")
+	}
+}
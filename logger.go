@@ -0,0 +1,25 @@
+package googleIDVerifier
+
+// Logger receives the structured logs of the verifiers and key sets, args being alternating keys and values.
+// A *slog.Logger satisfies it as is, as do the sugared loggers of zap:
+//
+//	v := googleIDVerifier.NewVerifier(googleIDVerifier.WithLogger(slog.Default()))
+//
+// Tokens are never logged, only their kid, iss and aud.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+}
+
+// logFailure logs a verification failure with the unverified kid, iss and aud of the token
+func logFailure(l Logger, token string, err error) {
+	args := []interface{}{"reason", string(FailureReasonOf(err)), "error", err.Error()}
+	if header, herr := decodeRawSegment(token, 0); herr == nil {
+		args = append(args, "kid", header["kid"])
+	}
+	if claims, cerr := decodeRawClaims(token); cerr == nil {
+		args = append(args, "iss", claims["iss"], "aud", claims["aud"])
+	}
+	l.Info("token verification failed", args...)
+}
@@ -0,0 +1,52 @@
+package googleIDVerifier
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type logRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *logRecorder) log(level string, msg string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprint(level, " ", msg, " ", args))
+}
+
+func (l *logRecorder) Debug(msg string, args ...interface{}) { l.log("DEBUG", msg, args...) }
+func (l *logRecorder) Info(msg string, args ...interface{})  { l.log("INFO", msg, args...) }
+func (l *logRecorder) Warn(msg string, args ...interface{})  { l.log("WARN", msg, args...) }
+
+func TestLogger(t *testing.T) {
+	certs, _ := getTestCerts()
+	fail := true
+	k := NewKeySet(nil, func() (*Certs, error) {
+		if fail {
+			return nil, ErrNoKeySource
+		}
+		return certs, nil
+	})
+	l := &logRecorder{}
+	k.Logger = l
+	v := NewVerifier(WithKeySet(k), WithLogger(l))
+
+	_, _ = v.VerifyIDToken(validTestToken, "aud")
+	fail = false
+	_, _ = v.VerifyIDToken(validTestToken, "aud")
+
+	logs := strings.Join(l.lines, "\n")
+	for _, expected := range []string{"WARN key fetch failed", "INFO keys fetched", "INFO token verification failed [reason other",
+		"reason expired", "iss https://accounts.google.com", "DEBUG keys missing or expired"} {
+		if !strings.Contains(logs, expected) {
+			t.Errorf("Expect %q in logs:\n%s", expected, logs)
+		}
+	}
+	if strings.Contains(logs, validTestToken) {
+		t.Error("Expect the token not to be logged")
+	}
+}
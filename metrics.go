@@ -0,0 +1,40 @@
+package googleIDVerifier
+
+const (
+	// MetricVerifications counts verifications, labelled by outcome: "ok" or the failure error code
+	MetricVerifications = "google_id_verifier_verifications_total"
+	// MetricVerificationDuration is the verification latency in seconds
+	MetricVerificationDuration = "google_id_verifier_verification_duration_seconds"
+	// MetricKeyFetches counts the key fetches of a KeySet, labelled by outcome: "ok" or "error"
+	MetricKeyFetches = "google_id_verifier_key_fetches_total"
+	// MetricKeyFetchDuration is the key fetch latency in seconds, labelled by outcome
+	MetricKeyFetchDuration = "google_id_verifier_key_fetch_duration_seconds"
+	// MetricKeyLookups counts the key lookups of a KeySet, labelled by result: "hit" when served from
+	// its cache, "miss" when the keys had to be fetched first and "unknown_kid" when the token kid was not cached
+	MetricKeyLookups = "google_id_verifier_key_lookups_total"
+	// MetricFailureLogs counts the failures seen by a FailureLogger, labelled by code, the failure error code,
+	// and by suppressed: "true" when the rate limit kept it out of the logs, else "false"
+	MetricFailureLogs = "google_id_verifier_failure_logs_total"
+)
+
+// Metrics receives the verifier telemetry. Adapters exist for Prometheus (metrics/prometheus)
+// and statsd (metrics/statsd). Label names must be the same on every call for a metric name.
+type Metrics interface {
+	IncCounter(name string, labels map[string]string)
+	ObserveHistogram(name string, value float64, labels map[string]string)
+	SetGauge(name string, value float64, labels map[string]string)
+}
+
+func outcome(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	return failureCode(err)
+}
+
+func fetchOutcome(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	return "error"
+}
@@ -0,0 +1,134 @@
+// Package prometheus exposes the verifier metrics as Prometheus collectors
+package prometheus
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics implements googleIDVerifier.Metrics, creating and registering a collector
+// for each metric name on first use
+type Metrics struct {
+	// OnError, if set, is called when a collector cannot be registered, e.g. for a name already
+	// registered with other labels. The samples of the metric are dropped until it can be.
+	OnError func(err error)
+
+	registerer prometheus.Registerer
+
+	mu         sync.Mutex
+	counters   map[string]*prometheus.CounterVec
+	histograms map[string]*prometheus.HistogramVec
+	gauges     map[string]*prometheus.GaugeVec
+}
+
+// New returns Metrics registering its collectors with r, prometheus.DefaultRegisterer when nil
+func New(r prometheus.Registerer) *Metrics {
+	if r == nil {
+		r = prometheus.DefaultRegisterer
+	}
+	return &Metrics{
+		registerer: r,
+		counters:   map[string]*prometheus.CounterVec{},
+		histograms: map[string]*prometheus.HistogramVec{},
+		gauges:     map[string]*prometheus.GaugeVec{},
+	}
+}
+
+// IncCounter increments a counter
+func (m *Metrics) IncCounter(name string, labels map[string]string) {
+	m.mu.Lock()
+	c, ok := m.counters[name]
+	if !ok {
+		collector, err := m.register(prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: name}, labelNames(labels)))
+		if err == nil {
+			if c, ok = collector.(*prometheus.CounterVec); !ok {
+				err = fmt.Errorf("%s is registered with another type", name)
+			}
+		}
+		if err != nil {
+			m.mu.Unlock()
+			m.fail(err)
+			return
+		}
+		m.counters[name] = c
+	}
+	m.mu.Unlock()
+	c.With(labels).Inc()
+}
+
+// ObserveHistogram records a value in a histogram with the default buckets
+func (m *Metrics) ObserveHistogram(name string, value float64, labels map[string]string) {
+	m.mu.Lock()
+	h, ok := m.histograms[name]
+	if !ok {
+		collector, err := m.register(prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: name}, labelNames(labels)))
+		if err == nil {
+			if h, ok = collector.(*prometheus.HistogramVec); !ok {
+				err = fmt.Errorf("%s is registered with another type", name)
+			}
+		}
+		if err != nil {
+			m.mu.Unlock()
+			m.fail(err)
+			return
+		}
+		m.histograms[name] = h
+	}
+	m.mu.Unlock()
+	h.With(labels).Observe(value)
+}
+
+// SetGauge sets a gauge
+func (m *Metrics) SetGauge(name string, value float64, labels map[string]string) {
+	m.mu.Lock()
+	g, ok := m.gauges[name]
+	if !ok {
+		collector, err := m.register(prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: name}, labelNames(labels)))
+		if err == nil {
+			if g, ok = collector.(*prometheus.GaugeVec); !ok {
+				err = fmt.Errorf("%s is registered with another type", name)
+			}
+		}
+		if err != nil {
+			m.mu.Unlock()
+			m.fail(err)
+			return
+		}
+		m.gauges[name] = g
+	}
+	m.mu.Unlock()
+	g.With(labels).Set(value)
+}
+
+// register registers c, or returns the collector already registered for it, e.g. by another
+// Metrics sharing the registry
+func (m *Metrics) register(c prometheus.Collector) (prometheus.Collector, error) {
+	err := m.registerer.Register(c)
+	var registered prometheus.AlreadyRegisteredError
+	if errors.As(err, &registered) {
+		return registered.ExistingCollector, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (m *Metrics) fail(err error) {
+	if m.OnError != nil {
+		m.OnError(err)
+	}
+}
+
+func labelNames(labels map[string]string) []string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,51 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := New(registry)
+
+	m.IncCounter("verifications_total", map[string]string{"outcome": "ok"})
+	m.IncCounter("verifications_total", map[string]string{"outcome": "ok"})
+	m.ObserveHistogram("verification_duration_seconds", 0.01, map[string]string{"outcome": "ok"})
+	m.SetGauge("keys", 2, nil)
+
+	if v := testutil.ToFloat64(m.counters["verifications_total"].With(prometheus.Labels{"outcome": "ok"})); v != 2 {
+		t.Errorf("Expect counter to be 2, got %v", v)
+	}
+	if v := testutil.ToFloat64(m.gauges["keys"].With(nil)); v != 2 {
+		t.Errorf("Expect gauge to be 2, got %v", v)
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(families) != 3 {
+		t.Errorf("Expect 3 registered metrics, got %d", len(families))
+	}
+}
+
+func TestMetricsSharedRegistry(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	first, second := New(registry), New(registry)
+	var errs []error
+	second.OnError = func(err error) { errs = append(errs, err) }
+
+	first.IncCounter("verifications_total", map[string]string{"outcome": "ok"})
+	second.IncCounter("verifications_total", map[string]string{"outcome": "ok"})
+	if v := testutil.ToFloat64(first.counters["verifications_total"].With(prometheus.Labels{"outcome": "ok"})); v != 2 {
+		t.Errorf("Expect both Metrics to record on the registered counter, got %v", v)
+	}
+
+	second.IncCounter("verifications_total_other", map[string]string{"outcome": "ok"})
+	second.SetGauge("verifications_total", 1, map[string]string{"outcome": "ok"})
+	if len(errs) != 1 {
+		t.Errorf("Expect the conflicting gauge to be reported, got %v", errs)
+	}
+}
@@ -0,0 +1,60 @@
+// Package statsd sends the verifier metrics to a statsd daemon over UDP, with labels as
+// DogStatsD tags, which Datadog agents and Telegraf understand.
+package statsd
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// Client implements googleIDVerifier.Metrics over UDP
+type Client struct {
+	conn   net.Conn
+	prefix string
+}
+
+// New returns a client sending to the statsd daemon at addr, e.g. "127.0.0.1:8125",
+// prepending prefix to every metric name
+func New(addr string, prefix string) (*Client, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, prefix: prefix}, nil
+}
+
+// IncCounter increments a counter
+func (c *Client) IncCounter(name string, labels map[string]string) {
+	c.send(name, "1", "c", labels)
+}
+
+// ObserveHistogram records a value in a histogram
+func (c *Client) ObserveHistogram(name string, value float64, labels map[string]string) {
+	c.send(name, fmt.Sprint(value), "h", labels)
+}
+
+// SetGauge sets a gauge
+func (c *Client) SetGauge(name string, value float64, labels map[string]string) {
+	c.send(name, fmt.Sprint(value), "g", labels)
+}
+
+// Close closes the UDP socket
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) send(name string, value string, kind string, labels map[string]string) {
+	line := c.prefix + name + ":" + value + "|" + kind
+	if len(labels) > 0 {
+		tags := make([]string, 0, len(labels))
+		for k, v := range labels {
+			tags = append(tags, k+":"+v)
+		}
+		sort.Strings(tags)
+		line += "|#" + strings.Join(tags, ",")
+	}
+	// metrics are best effort, a lost datagram is not an error worth surfacing
+	_, _ = c.conn.Write([]byte(line))
+}
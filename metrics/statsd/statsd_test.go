@@ -0,0 +1,36 @@
+package statsd
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	c, err := New(pc.LocalAddr().String(), "app.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.IncCounter("verifications", map[string]string{"outcome": "ok", "aud": "x"})
+	c.ObserveHistogram("latency", 0.5, nil)
+
+	buf := make([]byte, 512)
+	for _, expected := range []string{"app.verifications:1|c|#aud:x,outcome:ok", "app.latency:0.5|h"} {
+		_ = pc.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf[:n]) != expected {
+			t.Errorf("Expect %s, got %s", expected, buf[:n])
+		}
+	}
+}
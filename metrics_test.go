@@ -0,0 +1,59 @@
+package googleIDVerifier
+
+import "testing"
+
+type metricsRecorder struct {
+	counters   map[string]int
+	histograms int
+}
+
+func (m *metricsRecorder) IncCounter(name string, labels map[string]string) {
+	m.counters[name+" "+labels["outcome"]]++
+}
+
+func (m *metricsRecorder) ObserveHistogram(string, float64, map[string]string) {
+	m.histograms++
+}
+
+func (m *metricsRecorder) SetGauge(string, float64, map[string]string) {}
+
+func TestVerifierMetrics(t *testing.T) {
+	certs, _ := getTestCerts()
+	defer func(keySet *KeySet) { googleKeySet = keySet }(googleKeySet)
+	googleKeySet = NewKeySet(certs, nil)
+
+	m := &metricsRecorder{counters: map[string]int{}}
+	v := &CertsVerifier{Metrics: m}
+	_, _ = v.VerifyIDToken(validTestToken, "aud")
+	_, _ = v.VerifyIDToken("garbage", "aud")
+
+	if m.counters[MetricVerifications+" "+ErrTokenUsedTooLate.Error()] != 1 ||
+		m.counters[MetricVerifications+" "+ErrInvalidToken.Error()] != 1 || m.histograms != 2 {
+		t.Errorf("Unexpected metrics %+v", m)
+	}
+}
+
+func TestKeySetMetrics(t *testing.T) {
+	certs, _ := getTestCerts()
+	fail := true
+	k := NewKeySet(nil, func() (*Certs, error) {
+		if fail {
+			return nil, ErrNoKeySource
+		}
+		return certs, nil
+	})
+	m := &metricsRecorder{counters: map[string]int{}}
+	k.Metrics = m
+
+	_, _ = k.VerifySignatureOnly(validTestToken)
+	fail = false
+	_, _ = k.VerifySignatureOnly(validTestToken)
+	_, _ = k.VerifySignatureOnly(validTestToken)
+
+	if m.counters[MetricKeyFetches+" error"] != 1 || m.counters[MetricKeyFetches+" ok"] != 1 || m.histograms != 2 {
+		t.Errorf("Unexpected fetch metrics %+v", m)
+	}
+	if m.counters[MetricKeyLookups+" "] != 3 {
+		t.Errorf("Expect 3 key lookups, got %+v", m)
+	}
+}
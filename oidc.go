@@ -0,0 +1,91 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ProviderConfig is the OpenID Connect discovery document of a provider, as served at
+// <issuer>/.well-known/openid-configuration
+type ProviderConfig struct {
+	Issuer                           string   `json:"issuer"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	AuthorizationEndpoint            string   `json:"authorization_endpoint,omitempty"`
+	TokenEndpoint                    string   `json:"token_endpoint,omitempty"`
+	UserinfoEndpoint                 string   `json:"userinfo_endpoint,omitempty"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported,omitempty"`
+}
+
+// Discover fetches the discovery document of the OpenID provider at issuer, e.g. https://accounts.google.com.
+// It fails with ErrWrongIssuer when the document is for another issuer, as required by OpenID Connect Discovery.
+func Discover(ctx context.Context, issuer string) (*ProviderConfig, error) {
+	return discover(ctx, nil, nil, issuer)
+}
+
+// discover is Discover with client, or defaultHTTPClient when nil, authenticating the request with
+// auth when not nil
+func discover(ctx context.Context, client *http.Client, auth RequestAuth, issuer string) (*ProviderConfig, error) {
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	url := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if auth != nil {
+		err = auth(req)
+		if err != nil {
+			return nil, fmt.Errorf("authenticating %s: %w", url, err)
+		}
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+
+	config := &ProviderConfig{}
+	err = json.NewDecoder(resp.Body).Decode(config)
+	if err != nil {
+		return nil, err
+	}
+	if config.Issuer != issuer {
+		return nil, fmt.Errorf("%w: discovery document of %s is for %s", ErrWrongIssuer, issuer, config.Issuer)
+	}
+	if config.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: discovery document of %s has no jwks_uri", ErrNoKeySource, issuer)
+	}
+	return config, nil
+}
+
+// NewOIDCVerifier returns a CertsVerifier for tokens of any OpenID provider, e.g. Firebase or Azure AD:
+// it accepts only the discovered issuer and verifies signatures with the keys of its jwks_uri. The
+// discovery document and the keys are fetched with the client set by WithHTTPClient, if any, and
+// authenticated with the KeyFetchAuth of the verifier for providers protecting them.
+//
+//	v, err := googleIDVerifier.NewOIDCVerifier(ctx, "https://login.microsoftonline.com/<tenant>/v2.0",
+//		googleIDVerifier.WithAudiences(clientID))
+func NewOIDCVerifier(ctx context.Context, issuer string, opts ...Option) (*CertsVerifier, error) {
+	v := &CertsVerifier{}
+	for _, opt := range append([]Option{WithAllowedAlgorithms(rs256, es256)}, opts...) {
+		opt(v)
+	}
+	config, err := discover(ctx, v.HTTPClient, v.KeyFetchAuth, issuer)
+	if err != nil {
+		return nil, err
+	}
+	v.Issuers = []string{config.Issuer}
+	if v.KeySet == nil {
+		v.KeySet = NewKeySetContext(nil, func(ctx context.Context) (*Certs, error) {
+			return fetchCertsWithAuth(ctx, v.HTTPClient, v.KeyFetchAuth, config.JWKSURI)
+		})
+	}
+	return v, nil
+}
@@ -0,0 +1,67 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewOIDCVerifier(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issuer string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_ = json.NewEncoder(w).Encode(ProviderConfig{Issuer: issuer, JWKSURI: issuer + "/keys"})
+		case "/keys":
+			_ = json.NewEncoder(w).Encode(response{Keys: []*key{encodeECKey("oidc", &priv.PublicKey)}})
+		}
+	}))
+	defer srv.Close()
+	issuer = srv.URL
+
+	ctx := context.Background()
+	var requests int
+	client := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		requests++
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+	v, err := NewOIDCVerifier(ctx, issuer, WithAudiences("client-id"), WithHTTPClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	claims := map[string]interface{}{"iss": issuer, "aud": "client-id", "sub": "1", "iat": now.Unix(), "exp": now.Add(time.Hour).Unix()}
+	if _, err = v.VerifyIDTokenContext(ctx, signES256(t, priv, "oidc", claims)); err != nil {
+		t.Error(err)
+	}
+
+	if requests != 2 {
+		t.Errorf("Expect discovery and keys to be fetched with the client, got %d requests", requests)
+	}
+
+	claims["iss"] = "https://accounts.google.com"
+	if _, err = v.VerifyIDToken(signES256(t, priv, "oidc", claims)); !errors.Is(err, ErrWrongIssuer) {
+		t.Errorf("Expect ErrWrongIssuer for another issuer, got %v", err)
+	}
+
+	if _, err = Discover(ctx, issuer+"/other"); err == nil {
+		t.Error("Expect discovery to fail for an unknown issuer")
+	}
+}
+
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
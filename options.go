@@ -0,0 +1,240 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Option configures a CertsVerifier built by NewVerifier
+type Option func(v *CertsVerifier)
+
+// NewVerifier returns a CertsVerifier configured by the given options. Settings without an option
+// fall back to the package variables, which are only defaults.
+//
+//	v := googleIDVerifier.NewVerifier(
+//		googleIDVerifier.WithAudiences("xxxxxx-yyyyyyy.apps.googleusercontent.com"),
+//		googleIDVerifier.WithClockSkew(time.Minute),
+//	)
+func NewVerifier(opts ...Option) *CertsVerifier {
+	v := &CertsVerifier{}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// NewStaticVerifier returns a CertsVerifier checking signatures only against the given pinned keys,
+// for air-gapped deployments: it never fetches keys, even when they expire. Rotate them with
+// v.KeySet.SetCerts. Options making network calls, such as a RevocationSampler, should not be added.
+func NewStaticVerifier(certs *Certs, opts ...Option) *CertsVerifier {
+	return NewVerifier(append([]Option{WithKeySet(NewKeySet(certs, nil))}, opts...)...)
+}
+
+// WithAudiences sets the audiences accepted when VerifyIDToken is called without any
+func WithAudiences(audiences ...string) Option {
+	return func(v *CertsVerifier) {
+		v.DefaultAudience = audiences
+	}
+}
+
+// WithAudienceMatcher adds a matcher accepting the tokens with an audience for which it returns true,
+// besides the audiences listed, e.g. AudiencePrefix("https://api.example.com/")
+func WithAudienceMatcher(match func(aud string) bool) Option {
+	return func(v *CertsVerifier) {
+		v.AudienceMatchers = append(v.AudienceMatchers, match)
+	}
+}
+
+// WithIssuers sets the accepted issuers instead of the package Issuers
+func WithIssuers(issuers ...string) Option {
+	return func(v *CertsVerifier) {
+		v.Issuers = issuers
+	}
+}
+
+// WithClockSkew sets the tolerance on the token times instead of the package ClockSkew
+func WithClockSkew(d time.Duration) Option {
+	return func(v *CertsVerifier) {
+		v.ClockSkew = d
+	}
+}
+
+// WithMaxTokenLifetime sets how far in the future tokens may expire instead of the package MaxTokenLifetime
+func WithMaxTokenLifetime(d time.Duration) Option {
+	return func(v *CertsVerifier) {
+		v.MaxTokenLifetime = d
+	}
+}
+
+// WithClock sets the clock telling the current time when checking the token times
+func WithClock(clock func() time.Time) Option {
+	return func(v *CertsVerifier) {
+		v.Clock = clock
+	}
+}
+
+// WithReferenceClock sets the trusted time source SelfTest checks the verifier clock against
+func WithReferenceClock(clock func(ctx context.Context) (time.Time, error)) Option {
+	return func(v *CertsVerifier) {
+		v.ReferenceClock = clock
+	}
+}
+
+// WithAllowExpired tolerates expired tokens which pass every other check: their claims
+// are returned along with ErrTokenExpired, see CertsVerifier.AllowExpired
+func WithAllowExpired() Option {
+	return func(v *CertsVerifier) {
+		v.AllowExpired = true
+	}
+}
+
+// WithKeySet sets the keys verifying the token signatures instead of the Google certs
+func WithKeySet(k *KeySet) Option {
+	return func(v *CertsVerifier) {
+		v.KeySet = k
+	}
+}
+
+// WithKeySource sets the source of the keys verifying the token signatures instead of the Google certs
+func WithKeySource(source KeySource) Option {
+	return func(v *CertsVerifier) {
+		v.KeySet = NewKeySetFromSource(source)
+	}
+}
+
+// WithCertsURL fetches the keys verifying the token signatures from url instead of the Google
+// endpoint, e.g. a private proxy or a test server, in JWKS or PEM certs format. The client set
+// by WithHTTPClient is used, and the auth set by WithKeyFetchAuth.
+func WithCertsURL(url string) Option {
+	return func(v *CertsVerifier) {
+		v.KeySet = NewKeySetFromSource(KeySourceFunc(func(ctx context.Context) (*Certs, error) {
+			return fetchCertsWithAuth(ctx, v.HTTPClient, v.KeyFetchAuth, url)
+		}))
+	}
+}
+
+// WithKeyFetchAuth authenticates the fetches of the keys of WithCertsURL and NewOIDCVerifier, and of
+// the discovery document, see CertsVerifier.KeyFetchAuth
+func WithKeyFetchAuth(auth RequestAuth) Option {
+	return func(v *CertsVerifier) {
+		v.KeyFetchAuth = auth
+	}
+}
+
+// WithCertCache shares the Google certs fetched by the verifier through cache, see CachedKeySource.
+// The client set by WithHTTPClient is used to fetch them.
+func WithCertCache(cache CertCache) Option {
+	return func(v *CertsVerifier) {
+		source := KeySourceFunc(func(ctx context.Context) (*Certs, error) {
+			return fetchCerts(ctx, v.HTTPClient, googleOAuth2FederatedSignOnCertsURL)
+		})
+		v.KeySet = NewKeySetFromSource(CachedKeySource(cache, certCacheKey(googleOAuth2FederatedSignOnCertsURL), source))
+	}
+}
+
+// WithAllowedAlgorithms sets the accepted token algorithms instead of the package AllowedAlgorithms,
+// e.g. ES256 for tokens signed with P-256 keys
+func WithAllowedAlgorithms(algorithms ...string) Option {
+	return func(v *CertsVerifier) {
+		v.Algorithms = algorithms
+	}
+}
+
+// WithSignatureFirst verifies the token signature before its claims, see CertsVerifier.SignatureFirst
+func WithSignatureFirst() Option {
+	return func(v *CertsVerifier) {
+		v.SignatureFirst = true
+	}
+}
+
+// WithFastDecode decodes the tokens with the allocation-free path, see CertsVerifier.FastDecode
+func WithFastDecode() Option {
+	return func(v *CertsVerifier) {
+		v.FastDecode = true
+	}
+}
+
+// WithPinnedKeyIDs rejects the tokens signed with any other kid with ErrPublicKeyNotFound,
+// even when the key endpoint serves it, see CertsVerifier.PinnedKeyIDs
+func WithPinnedKeyIDs(kids ...string) Option {
+	return func(v *CertsVerifier) {
+		v.PinnedKeyIDs = kids
+	}
+}
+
+// WithHTTPClient sets the client fetching the Google certs
+func WithHTTPClient(c *http.Client) Option {
+	return func(v *CertsVerifier) {
+		v.HTTPClient = c
+	}
+}
+
+// WithClientCertificates fetches the keys over mutual TLS, presenting certs to the key endpoints
+// requiring them. It sets the HTTPClient to a NewTLSClient, replacing the one of WithHTTPClient.
+func WithClientCertificates(certs ...tls.Certificate) Option {
+	return WithHTTPClient(NewTLSClient(&tls.Config{Certificates: certs}))
+}
+
+// WithRequiredClaims adds claims checked after the standard checks
+func WithRequiredClaims(requirements ...ClaimRequirement) Option {
+	return func(v *CertsVerifier) {
+		v.RequiredClaims = append(v.RequiredClaims, requirements...)
+	}
+}
+
+// WithMetrics sets the receiver of verification counts and latencies. The key fetch metrics are
+// sent to KeySet.Metrics, e.g. GoogleKeySet().Metrics for the Google certs.
+func WithMetrics(m Metrics) Option {
+	return func(v *CertsVerifier) {
+		v.Metrics = m
+	}
+}
+
+// WithLogger sets the logger of the verification failures. The key fetches are logged by
+// KeySet.Logger, e.g. GoogleKeySet().Logger for the Google certs.
+func WithLogger(l Logger) Option {
+	return func(v *CertsVerifier) {
+		v.Logger = l
+	}
+}
+
+// WithClaimValidator adds a custom check of the claims, run after the standard checks succeed
+func WithClaimValidator(validate func(claimSet *ClaimSet) error) Option {
+	return func(v *CertsVerifier) {
+		v.ClaimValidators = append(v.ClaimValidators, validate)
+	}
+}
+
+// WithNonce rejects tokens whose nonce is not the one sent in the authentication request, with ErrWrongNonce
+func WithNonce(expected string) Option {
+	return WithClaimValidator(func(claimSet *ClaimSet) error {
+		if subtle.ConstantTimeCompare([]byte(claimSet.Nonce), []byte(expected)) != 1 {
+			return ErrWrongNonce
+		}
+		return nil
+	})
+}
+
+// WithAuthorizedParties rejects tokens whose azp, the client which requested the token, is missing
+// or not one of parties, with ErrAuthorizedPartyNotAllowed. Useful when an Android client gets
+// tokens for the audience of a backend.
+func WithAuthorizedParties(parties ...string) Option {
+	return WithClaimValidator(func(claimSet *ClaimSet) error {
+		if !contains(parties, claimSet.AuthorizedParty) {
+			return fmt.Errorf("%w: %s", ErrAuthorizedPartyNotAllowed, claimSet.AuthorizedParty)
+		}
+		return nil
+	})
+}
+
+// WithAllowedHostedDomains rejects tokens whose hd claim, the Google Workspace domain of the user,
+// is missing or not one of domains, with ErrHostedDomainNotAllowed
+func WithAllowedHostedDomains(domains ...string) Option {
+	return WithClaimValidator(func(claimSet *ClaimSet) error {
+		return Claims(claimSet).RequireHostedDomain(domains...).Err()
+	})
+}
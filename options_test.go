@@ -0,0 +1,134 @@
+package googleIDVerifier
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewVerifier(t *testing.T) {
+	certs, _ := getTestCerts()
+	_, claimSet, _ := parseJWT(validTestToken)
+	nowFn = func() time.Time { return time.Unix(claimSet.Exp, 0).Add(2 * time.Minute) }
+	defer func() { nowFn = time.Now }()
+
+	lenient := NewVerifier(WithKeySet(NewKeySet(certs, nil)), WithAudiences(claimSet.Aud))
+	strict := NewVerifier(WithKeySet(NewKeySet(certs, nil)), WithAudiences(claimSet.Aud),
+		WithClockSkew(time.Minute), WithIssuers("https://accounts.google.com"))
+
+	if _, err := lenient.VerifyIDToken(validTestToken); err != nil {
+		t.Errorf("Expect default clock skew to accept the token, got %v", err)
+	}
+	if _, err := strict.VerifyIDToken(validTestToken); !errors.Is(err, ErrTokenUsedTooLate) {
+		t.Errorf("Expect ErrTokenUsedTooLate with a one minute skew, got %v", err)
+	}
+
+	other := NewVerifier(WithKeySet(NewKeySet(certs, nil)), WithIssuers("accounts.google.com"))
+	if _, err := other.VerifyIDToken(validTestToken, claimSet.Aud); !errors.Is(err, ErrWrongIssuer) {
+		t.Errorf("Expect ErrWrongIssuer, got %v", err)
+	}
+}
+
+func TestNewStaticVerifier(t *testing.T) {
+	certs, err := LoadCertsFile("google-keys.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, claimSet, _ := parseJWT(validTestToken)
+	nowFn = func() time.Time { return time.Unix(claimSet.Exp, 0) }
+	defer func() { nowFn = time.Now }()
+
+	v := NewStaticVerifier(certs, WithAudiences(claimSet.Aud))
+	if _, err = v.VerifyIDToken(validTestToken); !errors.Is(err, ErrPublicKeyNotFound) {
+		t.Errorf("Expect ErrPublicKeyNotFound without fetching keys, got %v", err)
+	}
+
+	pinned, _ := getTestCerts()
+	pinned.Expiry = time.Now().Add(-time.Hour)
+	v.KeySet.SetCerts(pinned)
+	if _, err = v.VerifyIDToken(validTestToken); err != nil {
+		t.Errorf("Expect rotated expired pinned keys to be used, got %v", err)
+	}
+}
+
+func TestClaimValidators(t *testing.T) {
+	certs, _ := getTestCerts()
+	_, claimSet, _ := parseJWT(validTestToken)
+	nowFn = func() time.Time { return time.Unix(claimSet.Exp, 0) }
+	defer func() { nowFn = time.Now }()
+	verify := func(opts ...Option) error {
+		_, err := VerifySignedJWTWithCerts(validTestToken, certs, []string{claimSet.Aud}, Issuers, MaxTokenLifetime, opts...)
+		return err
+	}
+
+	if err := verify(WithAuthorizedParties(claimSet.Aud)); err != nil {
+		t.Error(err)
+	}
+	if err := verify(WithAuthorizedParties("android-client")); !errors.Is(err, ErrAuthorizedPartyNotAllowed) {
+		t.Errorf("Expect ErrAuthorizedPartyNotAllowed, got %v", err)
+	}
+	if err := verify(WithNonce("n-0S6_WzA2Mj")); !errors.Is(err, ErrWrongNonce) {
+		t.Errorf("Expect ErrWrongNonce for a token without nonce, got %v", err)
+	}
+
+	errCustom := errors.New("custom")
+	err := verify(WithClaimValidator(func(c *ClaimSet) error {
+		if !c.EmailVerified {
+			return nil
+		}
+		return errCustom
+	}))
+	if !errors.Is(err, errCustom) {
+		t.Errorf("Expect the custom validator error, got %v", err)
+	}
+}
+
+func TestWithAllowedHostedDomains(t *testing.T) {
+	certs, _ := getTestCerts()
+	_, claimSet, _ := parseJWT(validTestToken)
+	nowFn = func() time.Time { return time.Unix(claimSet.Exp, 0) }
+	defer func() { nowFn = time.Now }()
+
+	// the test token is from a gmail.com account, without hd
+	_, err := NewStaticVerifier(certs, WithAllowedHostedDomains("example.com")).VerifyIDToken(validTestToken, claimSet.Aud)
+	verr := &VerificationError{}
+	if !errors.Is(err, ErrHostedDomainNotAllowed) || !errors.As(err, &verr) || verr.Claim != "hd" {
+		t.Errorf("Expect ErrHostedDomainNotAllowed for a token without hd, got %v", err)
+	}
+}
+
+func TestWithClockAndAllowExpired(t *testing.T) {
+	certs, _ := getTestCerts()
+	_, claimSet, _ := parseJWT(validTestToken)
+	clock := func() time.Time { return time.Unix(claimSet.Exp, 0) }
+
+	if _, err := NewStaticVerifier(certs, WithClock(clock)).VerifyIDToken(validTestToken, claimSet.Aud); err != nil {
+		t.Errorf("Expect the clock to make the token valid, got %v", err)
+	}
+
+	provisioned := false
+	v := NewStaticVerifier(certs, WithAllowExpired())
+	v.Provisioner = NewProvisioner(NewMemorySeenStore(), func(*ClaimSet) error {
+		provisioned = true
+		return nil
+	})
+	expired, err := v.VerifyIDToken(validTestToken, claimSet.Aud)
+	if !errors.Is(err, ErrTokenExpired) || FailureReasonOf(err) != FailureExpired {
+		t.Errorf("Expect ErrTokenExpired, got %v", err)
+	}
+	if expired == nil || expired.Sub != claimSet.Sub {
+		t.Errorf("Expect the claims of the expired token, got %+v", expired)
+	}
+	if provisioned {
+		t.Error("Expect expired tokens not to be provisioned")
+	}
+
+	for _, token := range []string{wrongSigToken, "garbage"} {
+		if claims, err := v.VerifyIDToken(token, claimSet.Aud); claims != nil || errors.Is(err, ErrTokenExpired) {
+			t.Errorf("Expect no claims for an invalid token, got %+v, %v", claims, err)
+		}
+	}
+	if claims, err := v.VerifyIDToken(validTestToken, "other"); claims != nil || !errors.Is(err, ErrWrongAudience) {
+		t.Errorf("Expect ErrWrongAudience before expiry, got %+v, %v", claims, err)
+	}
+}
@@ -0,0 +1,95 @@
+package googleIDVerifier
+
+import "sync"
+
+// SeenStore records the subjects which have been provisioned
+type SeenStore interface {
+	// Seen reports whether sub was provisioned
+	Seen(sub string) (bool, error)
+	// MarkSeen records that sub was provisioned
+	MarkSeen(sub string) error
+}
+
+// MemorySeenStore is an in-memory SeenStore, suitable for a single process
+type MemorySeenStore struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// NewMemorySeenStore returns an empty MemorySeenStore
+func NewMemorySeenStore() *MemorySeenStore {
+	return &MemorySeenStore{seen: map[string]bool{}}
+}
+
+// Seen reports whether sub is in the store
+func (s *MemorySeenStore) Seen(sub string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seen[sub], nil
+}
+
+// MarkSeen adds sub to the store
+func (s *MemorySeenStore) MarkSeen(sub string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seen[sub] = true
+	return nil
+}
+
+// Provisioner runs a callback the first time a subject is verified, e.g. to create its user record
+type Provisioner struct {
+	Store     SeenStore
+	Provision func(claimSet *ClaimSet) error
+
+	mu       sync.Mutex
+	inFlight map[string]*provisioning
+}
+
+type provisioning struct {
+	done chan struct{}
+	err  error
+}
+
+// NewProvisioner returns a Provisioner calling provision for subjects not in store
+func NewProvisioner(store SeenStore, provision func(claimSet *ClaimSet) error) *Provisioner {
+	return &Provisioner{Store: store, Provision: provision}
+}
+
+// Ensure calls Provision if the subject of the claims was never provisioned, and records it in the Store
+// once Provision succeeds, so a failure or a crash is retried on the next login. Concurrent calls for
+// a subject wait for the first one and share its result, the other subjects going on meanwhile.
+// Processes sharing a Store may still provision a new subject concurrently: Provision must be idempotent.
+func (p *Provisioner) Ensure(claimSet *ClaimSet) error {
+	p.mu.Lock()
+	if pending, ok := p.inFlight[claimSet.Sub]; ok {
+		p.mu.Unlock()
+		<-pending.done
+		return pending.err
+	}
+	if p.inFlight == nil {
+		p.inFlight = map[string]*provisioning{}
+	}
+	pending := &provisioning{done: make(chan struct{})}
+	p.inFlight[claimSet.Sub] = pending
+	p.mu.Unlock()
+
+	pending.err = p.ensure(claimSet)
+
+	p.mu.Lock()
+	delete(p.inFlight, claimSet.Sub)
+	p.mu.Unlock()
+	close(pending.done)
+	return pending.err
+}
+
+func (p *Provisioner) ensure(claimSet *ClaimSet) error {
+	seen, err := p.Store.Seen(claimSet.Sub)
+	if err != nil || seen {
+		return err
+	}
+	err = p.Provision(claimSet)
+	if err != nil {
+		return err
+	}
+	return p.Store.MarkSeen(claimSet.Sub)
+}
@@ -0,0 +1,86 @@
+package googleIDVerifier
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestProvisioner(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	fail := true
+	p := NewProvisioner(NewMemorySeenStore(), func(*ClaimSet) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if fail {
+			return errors.New("db down")
+		}
+		return nil
+	})
+	claimSet := &ClaimSet{}
+	claimSet.Sub = "114160320275393755424"
+
+	if err := p.Ensure(claimSet); err == nil {
+		t.Error("Expect provisioning error")
+	}
+
+	fail = false
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.Ensure(claimSet); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 2 {
+		t.Errorf("Expect one retry after the failure and no more, got %d calls", calls)
+	}
+}
+
+// blockingSeenStore blocks the lookups of blocked until release is closed
+type blockingSeenStore struct {
+	*MemorySeenStore
+	blocked string
+	release chan struct{}
+}
+
+func (s *blockingSeenStore) Seen(sub string) (bool, error) {
+	if sub == s.blocked {
+		<-s.release
+	}
+	return s.MemorySeenStore.Seen(sub)
+}
+
+func TestProvisionerMarksSeenAfterProvision(t *testing.T) {
+	store := &blockingSeenStore{MemorySeenStore: NewMemorySeenStore(), blocked: "slow", release: make(chan struct{})}
+	p := NewProvisioner(store, func(claimSet *ClaimSet) error {
+		if seen, _ := store.MemorySeenStore.Seen(claimSet.Sub); seen {
+			t.Errorf("Expect %s not to be marked seen before provisioning", claimSet.Sub)
+		}
+		return nil
+	})
+	slow, fast := &ClaimSet{}, &ClaimSet{}
+	slow.Sub, fast.Sub = "slow", "fast"
+
+	done := make(chan error)
+	go func() { done <- p.Ensure(slow) }()
+	// a subject waiting on the store does not hold up the others
+	if err := p.Ensure(fast); err != nil {
+		t.Fatal(err)
+	}
+	close(store.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	for _, sub := range []string{"slow", "fast"} {
+		if seen, _ := store.Seen(sub); !seen {
+			t.Errorf("Expect %s to be marked seen once provisioned", sub)
+		}
+	}
+}
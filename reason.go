@@ -0,0 +1,122 @@
+package googleIDVerifier
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// RejectionReason is a reason for rejecting a token which is safe to give back to callers:
+// it never exposes key IDs, issuer lists or other configuration
+type RejectionReason string
+
+const (
+	// ReasonMalformed is a token which cannot be decoded or misses mandatory claims
+	ReasonMalformed RejectionReason = "malformed"
+	// ReasonExpired is a token used after its expiration time
+	ReasonExpired RejectionReason = "expired"
+	// ReasonNotYetValid is a token used before its issue time
+	ReasonNotYetValid RejectionReason = "not_yet_valid"
+	// ReasonWrongAudience is a token issued for another client
+	ReasonWrongAudience RejectionReason = "wrong_audience"
+	// ReasonNotAllowed is a valid token whose user is not allowed, e.g. by an email or hosted domain allow-list
+	ReasonNotAllowed RejectionReason = "not_allowed"
+	// ReasonInvalid covers every other rejection
+	ReasonInvalid RejectionReason = "invalid"
+)
+
+var reasonTitles = map[RejectionReason]string{
+	ReasonMalformed:     "Malformed token",
+	ReasonExpired:       "Expired token",
+	ReasonNotYetValid:   "Token not valid yet",
+	ReasonWrongAudience: "Token for another audience",
+	ReasonNotAllowed:    "User not allowed",
+	ReasonInvalid:       "Invalid token",
+}
+
+var reasonDescriptions = map[RejectionReason]string{
+	ReasonMalformed:     "The token is malformed.",
+	ReasonExpired:       "The token has expired, obtain a new one.",
+	ReasonNotYetValid:   "The token is not valid yet, check the client clock.",
+	ReasonWrongAudience: "The token was issued for another application.",
+	ReasonNotAllowed:    "The user is not allowed to access this resource.",
+	ReasonInvalid:       "The token is invalid.",
+}
+
+// ClientReason maps a verification error to a client-safe rejection reason
+func ClientReason(err error) RejectionReason {
+	var corrupt base64.CorruptInputError
+	var syntax *json.SyntaxError
+	var unmarshalType *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, ErrInvalidToken), errors.Is(err, ErrNoIssueTimeInToken), errors.Is(err, ErrNoExpirationTimeInToken),
+		errors.As(err, &corrupt), errors.As(err, &syntax), errors.As(err, &unmarshalType):
+		return ReasonMalformed
+	case errors.Is(err, ErrTokenUsedTooLate):
+		return ReasonExpired
+	case errors.Is(err, ErrTokenUsedTooEarly):
+		return ReasonNotYetValid
+	case errors.Is(err, ErrWrongAudience):
+		return ReasonWrongAudience
+	case errors.Is(err, ErrEmailNotAllowed), errors.Is(err, ErrEmailNotVerified), errors.Is(err, ErrHostedDomainNotAllowed),
+		errors.Is(err, ErrEntitlementNotAllowed), errors.Is(err, ErrAuthorizedPartyNotAllowed):
+		return ReasonNotAllowed
+	}
+	return ReasonInvalid
+}
+
+// Description returns a human readable explanation of the reason
+func (r RejectionReason) Description() string {
+	return reasonDescriptions[r]
+}
+
+// Status returns the HTTP status of a request rejected for the reason: 403 for ReasonNotAllowed,
+// the token being valid, and otherwise 401
+func (r RejectionReason) Status() int {
+	if r == ReasonNotAllowed {
+		return http.StatusForbidden
+	}
+	return http.StatusUnauthorized
+}
+
+// ProblemContentType is the media type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypePrefix prefixes the reason in the type URI of the problems, e.g.
+// urn:google-id-verifier:rejection:expired
+const ProblemTypePrefix = "urn:google-id-verifier:rejection:"
+
+// Problem is an RFC 7807 problem details body, describing a rejected request to clients
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	// Code is machine-readable, e.g. a RejectionReason
+	Code string `json:"code"`
+	// Detail is the client-safe explanation, which never exposes key IDs or configuration
+	Detail string `json:"detail,omitempty"`
+}
+
+// Problem returns the problem details of a request rejected for the reason
+func (r RejectionReason) Problem() *Problem {
+	return &Problem{
+		Type:   ProblemTypePrefix + string(r),
+		Title:  reasonTitles[r],
+		Status: r.Status(),
+		Code:   string(r),
+		Detail: r.Description(),
+	}
+}
+
+// WriteProblem writes p as an application/problem+json response with its Status
+func WriteProblem(w http.ResponseWriter, p *Problem) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// WriteRejection writes the problem details of the client-safe reason for err, see RejectionReason.Problem
+func WriteRejection(w http.ResponseWriter, err error) {
+	WriteProblem(w, ClientReason(err).Problem())
+}
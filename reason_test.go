@@ -0,0 +1,49 @@
+package googleIDVerifier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientReason(t *testing.T) {
+	certs, _ := getTestCerts()
+	_, err := VerifySignedJWTWithCerts("e30.!!!.x", certs, nil, Issuers, MaxTokenLifetime)
+	for expected, err := range map[RejectionReason]error{
+		ReasonMalformed:     err,
+		ReasonExpired:       ErrTokenUsedTooLate,
+		ReasonNotYetValid:   ErrTokenUsedTooEarly,
+		ReasonWrongAudience: checkAudiences(&ClaimSet{}, []string{"aud"}),
+		ReasonNotAllowed:    fmt.Errorf("%w: user@example.com", ErrEmailNotAllowed),
+		ReasonInvalid:       checkIssuer(&ClaimSet{}, Issuers),
+	} {
+		if reason := ClientReason(err); reason != expected {
+			t.Errorf("Expect %s for %v, got %s", expected, err, reason)
+		}
+	}
+	if ClientReason(ErrPublicKeyNotFound) != ReasonInvalid {
+		t.Error("Expect unknown keys to be reported as invalid")
+	}
+
+	rec := httptest.NewRecorder()
+	WriteRejection(rec, errors.New("kid 1234 not in https://internal/keys"))
+	problem := &Problem{}
+	_ = json.Unmarshal(rec.Body.Bytes(), problem)
+	if rec.Code != http.StatusUnauthorized || strings.Contains(rec.Body.String(), "1234") ||
+		rec.Header().Get("Content-Type") != ProblemContentType || *problem != *ReasonInvalid.Problem() {
+		t.Errorf("Unexpected rejection %d %s", rec.Code, rec.Body)
+	}
+	if problem.Type != "urn:google-id-verifier:rejection:invalid" || problem.Status != http.StatusUnauthorized ||
+		problem.Code != "invalid" || problem.Title == "" {
+		t.Errorf("Expect RFC 7807 members, got %+v", problem)
+	}
+	rec = httptest.NewRecorder()
+	WriteRejection(rec, ErrHostedDomainNotAllowed)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expect a 403 for a user not allowed, got %d", rec.Code)
+	}
+}
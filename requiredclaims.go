@@ -0,0 +1,78 @@
+package googleIDVerifier
+
+import "fmt"
+
+// ClaimType is the JSON type a claim is expected to have
+type ClaimType string
+
+const (
+	// ClaimString is a JSON string
+	ClaimString ClaimType = "string"
+	// ClaimBool is a JSON boolean
+	ClaimBool ClaimType = "bool"
+	// ClaimNumber is a JSON number
+	ClaimNumber ClaimType = "number"
+	// ClaimArray is a JSON array
+	ClaimArray ClaimType = "array"
+	// ClaimObject is a JSON object
+	ClaimObject ClaimType = "object"
+)
+
+// ClaimRequirement declares a claim which has to be of the given type and, if Required, present in the token
+type ClaimRequirement struct {
+	Name     string
+	Type     ClaimType
+	Required bool
+}
+
+// RequiredClaim returns a requirement for a claim that must be present with the given type
+func RequiredClaim(name string, t ClaimType) ClaimRequirement {
+	return ClaimRequirement{Name: name, Type: t, Required: true}
+}
+
+// OptionalClaim returns a requirement for a claim that may be absent, but must have the given type when present
+func OptionalClaim(name string, t ClaimType) ClaimRequirement {
+	return ClaimRequirement{Name: name, Type: t}
+}
+
+// CheckRequiredClaims checks the token payload against the given requirements.
+// It does not verify the token, so it should be called after the standard checks.
+func CheckRequiredClaims(token string, requirements []ClaimRequirement) error {
+	claims, err := decodeRawClaims(token)
+	if err != nil {
+		return err
+	}
+	return checkRequiredClaims(claims, requirements)
+}
+
+func checkRequiredClaims(claims map[string]interface{}, requirements []ClaimRequirement) error {
+	for _, r := range requirements {
+		value, ok := claims[r.Name]
+		if !ok || value == nil {
+			if r.Required {
+				return fmt.Errorf("%w: %s", ErrMissingClaim, r.Name)
+			}
+			continue
+		}
+		if claimType(value) != r.Type {
+			return fmt.Errorf("%w: %s is not a %s", ErrWrongClaimType, r.Name, r.Type)
+		}
+	}
+	return nil
+}
+
+func claimType(value interface{}) ClaimType {
+	switch value.(type) {
+	case string:
+		return ClaimString
+	case bool:
+		return ClaimBool
+	case float64:
+		return ClaimNumber
+	case []interface{}:
+		return ClaimArray
+	case map[string]interface{}:
+		return ClaimObject
+	}
+	return ""
+}
@@ -0,0 +1,119 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+var (
+	// Google tokeninfo endpoint
+	googleTokenInfoURL = "https://oauth2.googleapis.com/tokeninfo"
+)
+
+// DefaultRevocationMaxInFlight is the number of background checks of a RevocationSampler
+// with no MaxInFlight
+const DefaultRevocationMaxInFlight = 4
+
+// RevocationSampler cross-checks a sample of locally verified tokens against Google's tokeninfo
+// endpoint, to detect revoked or abnormal tokens without a remote call on every request
+type RevocationSampler struct {
+	// Rate is the fraction of tokens checked, between 0 and 1
+	Rate float64
+	// OnMismatch is called when tokeninfo disagrees with the local verification
+	OnMismatch func(claimSet *ClaimSet, reason string)
+	// OnError, if set, is called when a background check could not call tokeninfo
+	OnError func(err error)
+	// Client calls tokeninfo, a client with a 10s timeout when nil
+	Client *http.Client
+	// MaxInFlight caps the background checks running at once, DefaultRevocationMaxInFlight when <= 0.
+	// Sampled tokens are skipped while the cap is reached.
+	MaxInFlight int
+
+	once  sync.Once
+	slots chan struct{}
+}
+
+type tokenInfo struct {
+	Sub              string `json:"sub"`
+	Aud              string `json:"aud"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Sample checks the token in the background with a probability of Rate
+func (s *RevocationSampler) Sample(idToken string, claimSet *ClaimSet) {
+	if s.Rate <= 0 || rand.Float64() >= s.Rate {
+		return
+	}
+	s.once.Do(func() {
+		max := s.MaxInFlight
+		if max <= 0 {
+			max = DefaultRevocationMaxInFlight
+		}
+		s.slots = make(chan struct{}, max)
+	})
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		return
+	}
+	go func() {
+		defer func() { <-s.slots }()
+		err := s.Check(context.Background(), idToken, claimSet)
+		if err != nil && s.OnError != nil {
+			s.OnError(err)
+		}
+	}()
+}
+
+// Check calls tokeninfo for the token and reports a mismatch with the locally verified claims
+// to OnMismatch. The returned error is only about calling tokeninfo, including its non 200 responses
+// other than a rejection of the token.
+func (s *RevocationSampler) Check(ctx context.Context, idToken string, claimSet *ClaimSet) error {
+	form := url.Values{"id_token": {idToken}}.Encode()
+	req, err := http.NewRequest(http.MethodPost, googleTokenInfoURL, strings.NewReader(form))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	client := s.Client
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("%s: %s", googleTokenInfoURL, resp.Status)
+	}
+
+	info := &tokenInfo{}
+	err = json.NewDecoder(resp.Body).Decode(info)
+	if err != nil {
+		return err
+	}
+
+	var reason string
+	switch {
+	case resp.StatusCode == http.StatusBadRequest && info.Error != "":
+		reason = fmt.Sprintf("rejected by tokeninfo: %s %s", info.Error, info.ErrorDescription)
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("%s: %s", googleTokenInfoURL, resp.Status)
+	case info.Sub != claimSet.Sub:
+		reason = fmt.Sprintf("sub mismatch: %s", info.Sub)
+	case !contains(claimSet.audiences(), info.Aud):
+		reason = fmt.Sprintf("aud mismatch: %s", info.Aud)
+	}
+	if reason != "" && s.OnMismatch != nil {
+		s.OnMismatch(claimSet, reason)
+	}
+	return nil
+}
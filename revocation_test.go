@@ -0,0 +1,87 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRevocationSampler(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.RawQuery != "" {
+			t.Errorf("Expect the token in a POST body, got %s %s", r.Method, r.URL)
+		}
+		switch r.PostFormValue("id_token") {
+		case wrongSigToken:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": "invalid_token", "error_description": "Invalid Value"}`))
+			return
+		case "throttled":
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"sub": "114160320275393755424", "aud": "407408718192.apps.googleusercontent.com"}`))
+	}))
+	defer srv.Close()
+	defer func(url string) { googleTokenInfoURL = url }(googleTokenInfoURL)
+	googleTokenInfoURL = srv.URL
+
+	_, claimSet, _ := parseJWT(validTestToken)
+	var reasons []string
+	s := &RevocationSampler{Rate: 1, OnMismatch: func(_ *ClaimSet, reason string) {
+		reasons = append(reasons, reason)
+	}}
+
+	if err := s.Check(context.Background(), validTestToken, claimSet); err != nil {
+		t.Fatal(err)
+	}
+	if len(reasons) != 0 {
+		t.Errorf("Expect no mismatch, got %v", reasons)
+	}
+
+	if err := s.Check(context.Background(), wrongSigToken, claimSet); err != nil {
+		t.Fatal(err)
+	}
+	if len(reasons) != 1 || !strings.Contains(reasons[0], "invalid_token") {
+		t.Errorf("Expect rejection mismatch, got %v", reasons)
+	}
+
+	if err := s.Check(context.Background(), "throttled", claimSet); err == nil || len(reasons) != 1 {
+		t.Errorf("Expect an error and no mismatch for a 429, got %v %v", err, reasons)
+	}
+}
+
+func TestRevocationSamplerMaxInFlight(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		<-release
+		_, _ = w.Write([]byte(`{"sub": "114160320275393755424", "aud": "407408718192.apps.googleusercontent.com"}`))
+	}))
+	defer srv.Close()
+	defer func(url string) { googleTokenInfoURL = url }(googleTokenInfoURL)
+	googleTokenInfoURL = srv.URL
+
+	_, claimSet, _ := parseJWT(validTestToken)
+	s := &RevocationSampler{Rate: 1, MaxInFlight: 2}
+	for i := 0; i < 10; i++ {
+		s.Sample(validTestToken, claimSet)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 {
+		t.Errorf("Expect 2 checks in flight, got %d", calls)
+	}
+}
@@ -0,0 +1,165 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const selfTestKeyID = "self-test"
+
+var (
+	selfTestKeysOnce sync.Once
+	selfTestKey      *rsa.PrivateKey
+	selfTestECKey    *ecdsa.PrivateKey
+	selfTestKeyErr   error
+)
+
+// SelfTest checks the verifier is healthy, suitable for deep health checks: the certs can be obtained,
+// the clock agrees with the ReferenceClock when set, and a token minted with a throwaway key for each
+// allowed algorithm goes through the verification pipeline as configured, which catches misconfigured
+// issuers, algorithms, lifetimes or clock skew
+func (v *CertsVerifier) SelfTest(ctx context.Context) error {
+	certs, err := v.keySet().current(ctx)
+	if err != nil {
+		return fmt.Errorf("self-test: fetching certs: %w", err)
+	}
+	if len(certs.KeyIDs()) == 0 {
+		return fmt.Errorf("self-test: %w", ErrPublicKeyNotFound)
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	// the token is minted at the reference time, so the verification checks the clock of the verifier
+	now := v.now()
+	if v.ReferenceClock != nil {
+		reference, err := v.ReferenceClock(ctx)
+		if err != nil {
+			return fmt.Errorf("self-test: reading reference clock: %w", err)
+		}
+		if skew := now.Sub(reference); skew > v.clockSkew() || skew < -v.clockSkew() {
+			return fmt.Errorf("self-test: %w: clock off by %s", ErrClockSkew, skew)
+		}
+		now = reference
+	}
+
+	selfTestKeysOnce.Do(func() {
+		selfTestKey, selfTestKeyErr = rsa.GenerateKey(rand.Reader, 2048)
+		if selfTestKeyErr == nil {
+			selfTestECKey, selfTestKeyErr = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		}
+	})
+	if selfTestKeyErr != nil {
+		return fmt.Errorf("self-test: generating key: %w", selfTestKeyErr)
+	}
+	issuers := v.issuers()
+	if len(issuers) == 0 {
+		return fmt.Errorf("self-test: %w: no issuer configured", ErrWrongIssuer)
+	}
+	algorithms := v.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = AllowedAlgorithms
+	}
+
+	selfTestCerts := &Certs{
+		Keys:   map[string]*rsa.PublicKey{selfTestKeyID: &selfTestKey.PublicKey},
+		ECKeys: map[string]*ecdsa.PublicKey{selfTestKeyID: &selfTestECKey.PublicKey},
+		Expiry: time.Now().Add(time.Minute),
+	}
+	checks := claimChecks{
+		audiences:  []string{selfTestKeyID},
+		issuers:    issuers,
+		maxExpiry:  v.maxTokenLifetime(),
+		clockSkew:  v.clockSkew(),
+		now:        v.Clock,
+		algorithms: v.Algorithms,
+	}
+	tested := 0
+	for _, algorithm := range algorithms {
+		if algorithm != rs256 && algorithm != es256 {
+			continue
+		}
+		token, err := mintSelfTestToken(algorithm, issuers[0], now)
+		if err != nil {
+			return fmt.Errorf("self-test: minting %s token: %w", algorithm, err)
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		_, err = NewKeySet(selfTestCerts, nil).verify(ctx, token, checks, nil)
+		if err != nil {
+			return fmt.Errorf("self-test: verifying %s token: %w", algorithm, err)
+		}
+		tested++
+	}
+	if tested == 0 {
+		return fmt.Errorf("self-test: %w: none of %v", ErrUnsupportedAlgorithm, algorithms)
+	}
+	return nil
+}
+
+func mintSelfTestToken(algorithm string, issuer string, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": algorithm, "typ": "JWT", "kid": selfTestKeyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": issuer,
+		"aud": selfTestKeyID,
+		"sub": selfTestKeyID,
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	h := sha256.Sum256([]byte(signingInput))
+	var sig []byte
+	if algorithm == es256 {
+		r, s, err := ecdsa.Sign(rand.Reader, selfTestECKey, h[:])
+		if err != nil {
+			return "", err
+		}
+		sig = append(padded(r.Bytes(), 32), padded(s.Bytes(), 32)...)
+	} else {
+		sig, err = rsa.SignPKCS1v15(rand.Reader, selfTestKey, crypto.SHA256, h[:])
+		if err != nil {
+			return "", err
+		}
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// HTTPDateClock returns a reference clock, see CertsVerifier.ReferenceClock, reading the Date header
+// of a HEAD request to url with client, or a client with a 10 seconds timeout when nil. It is accurate
+// to the second, e.g. with https://www.googleapis.com.
+func HTTPDateClock(url string, client *http.Client) func(ctx context.Context) (time.Time, error) {
+	return func(ctx context.Context) (time.Time, error) {
+		c := client
+		if c == nil {
+			c = defaultHTTPClient
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+		if err != nil {
+			return time.Time{}, err
+		}
+		resp, err := c.Do(req)
+		if err != nil {
+			return time.Time{}, err
+		}
+		resp.Body.Close()
+		return http.ParseTime(resp.Header.Get("Date"))
+	}
+}
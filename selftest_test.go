@@ -0,0 +1,58 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSelfTest(t *testing.T) {
+	certs, _ := getTestCerts()
+	defer func(keySet *KeySet) { googleKeySet = keySet }(googleKeySet)
+	googleKeySet = NewKeySet(certs, nil)
+
+	v := &CertsVerifier{}
+	if err := v.SelfTest(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(lifetime time.Duration) { MaxTokenLifetime = lifetime }(MaxTokenLifetime)
+	MaxTokenLifetime = time.Minute
+	if err := v.SelfTest(context.Background()); !errors.Is(err, ErrExpirationTimeTooFarInFuture) {
+		t.Errorf("Expect misconfigured lifetime to be caught, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := v.SelfTest(ctx); err != context.Canceled {
+		t.Errorf("Expect context.Canceled, got %v", err)
+	}
+}
+
+func TestSelfTestAsConfigured(t *testing.T) {
+	certs, _ := getTestCerts()
+	ctx := context.Background()
+
+	if err := NewStaticVerifier(certs, WithAllowedAlgorithms("ES256")).SelfTest(ctx); err != nil {
+		t.Errorf("Expect an ES256 only verifier to pass, got %v", err)
+	}
+	if err := NewStaticVerifier(certs, WithAllowedAlgorithms("HS256")).SelfTest(ctx); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("Expect ErrUnsupportedAlgorithm, got %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	reference := HTTPDateClock(server.URL, nil)
+	if err := NewStaticVerifier(certs, WithReferenceClock(reference)).SelfTest(ctx); err != nil {
+		t.Errorf("Expect the clock to agree with the reference, got %v", err)
+	}
+	skewed := NewStaticVerifier(certs, WithReferenceClock(reference), WithClock(func() time.Time {
+		return time.Now().Add(10 * time.Minute)
+	}))
+	if err := skewed.SelfTest(ctx); !errors.Is(err, ErrClockSkew) {
+		t.Errorf("Expect ErrClockSkew, got %v", err)
+	}
+}
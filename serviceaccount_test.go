@@ -0,0 +1,63 @@
+package googleIDVerifier
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServiceAccountVerifier(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const email = "worker@my-project.iam.gserviceaccount.com"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+email {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(response{Keys: []*key{encodeECKey("sa", &priv.PublicKey)}})
+	}))
+	defer srv.Close()
+	defer func(url string) { serviceAccountCertsURL = url }(serviceAccountCertsURL)
+	serviceAccountCertsURL = srv.URL + "/"
+
+	certs, _ := getTestCerts()
+	v := NewServiceAccountVerifier([]string{email}, WithAudiences("https://api.example.com"), WithKeySet(NewKeySet(certs, nil)),
+		WithAllowedAlgorithms(rs256, es256))
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": email, "sub": email, "aud": "https://api.example.com", "iat": now.Unix(), "exp": now.Add(time.Hour).Unix(),
+	}
+	claimSet, err := v.VerifyIDToken(signES256(t, priv, "sa", claims))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimSet.Sub != email {
+		t.Errorf("Expect sub %s, got %s", email, claimSet.Sub)
+	}
+
+	claims["iss"] = "other@my-project.iam.gserviceaccount.com"
+	if _, err = v.VerifyIDToken(signES256(t, priv, "sa", claims)); !errors.Is(err, ErrWrongIssuer) {
+		t.Errorf("Expect ErrWrongIssuer for another account, got %v", err)
+	}
+
+	// a Google ID token of an account missing from the allow-list
+	_, google, _ := parseJWT(validTestToken)
+	nowFn = func() time.Time { return time.Unix(google.Exp, 0) }
+	defer func() { nowFn = time.Now }()
+	if _, err = v.VerifyIDToken(validTestToken, google.Aud); !errors.Is(err, ErrEmailNotAllowed) {
+		t.Errorf("Expect ErrEmailNotAllowed, got %v", err)
+	}
+	v.Emails = append(v.Emails, google.Email)
+	if _, err = v.VerifyIDToken(validTestToken, google.Aud); err != nil {
+		t.Errorf("Expect an allowed Google ID token to be accepted, got %v", err)
+	}
+}
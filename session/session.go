@@ -0,0 +1,199 @@
+// Package session exchanges verified ID tokens for opaque, revocable session tokens kept in a
+// pluggable store, and provides a middleware looking them up on incoming requests.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+)
+
+// DefaultLifetime of sessions
+const DefaultLifetime = 12 * time.Hour
+
+// sweepInterval is how often MemoryStore.Save evicts the expired sessions
+var sweepInterval = time.Minute
+
+var (
+	// ErrNotFound is returned by stores for unknown tokens
+	ErrNotFound = errors.New("Session not found")
+
+	// ErrExpired is returned for sessions past their expiry
+	ErrExpired = errors.New("Session expired")
+)
+
+// Session is an opaque token bound to verified claims
+type Session struct {
+	Token  string
+	Claims *googleIDVerifier.ClaimSet
+	Expiry time.Time
+}
+
+// Store persists sessions, e.g. in memory, Redis or an SQL database
+type Store interface {
+	Save(ctx context.Context, s *Session) error
+	// Load returns ErrNotFound for unknown tokens
+	Load(ctx context.Context, token string) (*Session, error)
+	Delete(ctx context.Context, token string) error
+}
+
+// Verifier verifies an ID token for the given audiences
+type Verifier interface {
+	VerifyIDToken(idToken string, audience ...string) (*googleIDVerifier.ClaimSet, error)
+}
+
+// ContextVerifier is a Verifier honoring the context of the request, such as googleIDVerifier.CertsVerifier.
+// Verifiers implementing it stop waiting for their keys when the request is cancelled.
+type ContextVerifier interface {
+	VerifyIDTokenContext(ctx context.Context, idToken string, audience ...string) (*googleIDVerifier.ClaimSet, error)
+}
+
+func verify(ctx context.Context, v Verifier, idToken string, audience []string) (*googleIDVerifier.ClaimSet, error) {
+	if cv, ok := v.(ContextVerifier); ok {
+		return cv.VerifyIDTokenContext(ctx, idToken, audience...)
+	}
+	return v.VerifyIDToken(idToken, audience...)
+}
+
+// Manager creates and looks up sessions
+type Manager struct {
+	Store Store
+	// Lifetime of new sessions, DefaultLifetime when zero
+	Lifetime time.Duration
+	// Extractor finds the session token in requests, googleIDVerifier.BearerExtractor when nil
+	Extractor googleIDVerifier.TokenExtractor
+}
+
+type contextKey struct{}
+
+// Exchange verifies the ID token and creates a session for its claims
+func (m *Manager) Exchange(ctx context.Context, v Verifier, idToken string, audience ...string) (*Session, error) {
+	claimSet, err := verify(ctx, v, idToken, audience)
+	if err != nil {
+		return nil, err
+	}
+	return m.Create(ctx, claimSet)
+}
+
+// Create stores a new session with a random token for the claims
+func (m *Manager) Create(ctx context.Context, claimSet *googleIDVerifier.ClaimSet) (*Session, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return nil, err
+	}
+	lifetime := m.Lifetime
+	if lifetime == 0 {
+		lifetime = DefaultLifetime
+	}
+	s := &Session{
+		Token:  base64.RawURLEncoding.EncodeToString(b),
+		Claims: claimSet,
+		Expiry: time.Now().Add(lifetime),
+	}
+	err = m.Store.Save(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Lookup returns the session of the token, or ErrExpired once it is past its expiry
+func (m *Manager) Lookup(ctx context.Context, token string) (*Session, error) {
+	s, err := m.Store.Load(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(s.Expiry) {
+		_ = m.Store.Delete(ctx, token)
+		return nil, ErrExpired
+	}
+	return s, nil
+}
+
+// Revoke deletes the session of the token
+func (m *Manager) Revoke(ctx context.Context, token string) error {
+	return m.Store.Delete(ctx, token)
+}
+
+// Middleware looks up the session of the token of each request and stores it in the
+// request context, see FromContext. Requests without a valid session get a 401.
+func (m *Manager) Middleware(next http.Handler) http.Handler {
+	extractor := m.Extractor
+	if extractor == nil {
+		extractor = googleIDVerifier.BearerExtractor()
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := extractor.ExtractToken(r.Header)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		s, err := m.Lookup(r.Context(), token)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, s)))
+	})
+}
+
+// FromContext returns the session stored by Middleware
+func FromContext(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(contextKey{}).(*Session)
+	return s, ok
+}
+
+// MemoryStore keeps sessions in memory, for a single process
+type MemoryStore struct {
+	mu        sync.RWMutex
+	sessions  map[string]*Session
+	lastSweep time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: map[string]*Session{}}
+}
+
+// Save stores the session. Once a minute it also evicts the expired sessions, so the abandoned ones,
+// never looked up again, do not pile up.
+func (s *MemoryStore) Save(_ context.Context, session *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now := time.Now(); now.Sub(s.lastSweep) >= sweepInterval {
+		for token, saved := range s.sessions {
+			if now.After(saved.Expiry) {
+				delete(s.sessions, token)
+			}
+		}
+		s.lastSweep = now
+	}
+	s.sessions[session.Token] = session
+	return nil
+}
+
+// Load returns the session of the token
+func (s *MemoryStore) Load(_ context.Context, token string) (*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	session, ok := s.sessions[token]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return session, nil
+}
+
+// Delete removes the session of the token
+func (s *MemoryStore) Delete(_ context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, token)
+	return nil
+}
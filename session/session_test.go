@@ -0,0 +1,83 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+	"github.com/fafg/google-id-verifier/idptest"
+)
+
+type idpVerifier struct {
+	idp *idptest.IDP
+}
+
+func (v *idpVerifier) VerifyIDToken(idToken string, audience ...string) (*googleIDVerifier.ClaimSet, error) {
+	return googleIDVerifier.VerifySignedJWTWithCerts(idToken, v.idp.Certs(), audience,
+		googleIDVerifier.Issuers, googleIDVerifier.MaxTokenLifetime)
+}
+
+func TestSession(t *testing.T) {
+	idp, err := idptest.New("client-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idp.Close()
+	idToken, _ := idp.Token(nil)
+
+	m := &Manager{Store: NewMemoryStore()}
+	ctx := context.Background()
+	s, err := m.Exchange(ctx, &idpVerifier{idp: idp}, idToken, "client-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := FromContext(r.Context())
+		if !ok {
+			t.Error("Expect session in context")
+			return
+		}
+		_, _ = w.Write([]byte(s.Claims.Email))
+	}))
+	call := func(token string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := call(s.Token); rec.Code != http.StatusOK || rec.Body.String() != "user@example.com" {
+		t.Errorf("Expect session to be found, got %d %s", rec.Code, rec.Body)
+	}
+
+	_ = m.Revoke(ctx, s.Token)
+	if rec := call(s.Token); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expect revoked session to be rejected, got %d", rec.Code)
+	}
+
+	m.Lifetime = -1
+	expired, _ := m.Create(ctx, s.Claims)
+	if _, err = m.Lookup(ctx, expired.Token); err != ErrExpired {
+		t.Errorf("Expect ErrExpired, got %v", err)
+	}
+}
+
+func TestMemoryStoreEvictsExpiredSessions(t *testing.T) {
+	defer func(d time.Duration) { sweepInterval = d }(sweepInterval)
+	sweepInterval = 0
+	ctx := context.Background()
+	store := NewMemoryStore()
+	_ = store.Save(ctx, &Session{Token: "abandoned", Expiry: time.Now().Add(-time.Second)})
+	_ = store.Save(ctx, &Session{Token: "active", Expiry: time.Now().Add(time.Hour)})
+	if _, err := store.Load(ctx, "abandoned"); err != ErrNotFound {
+		t.Errorf("Expect the abandoned session to be evicted, got %v", err)
+	}
+	if _, err := store.Load(ctx, "active"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,129 @@
+package sidecar
+
+import (
+	"net/http"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+)
+
+// OpenAPI returns the OpenAPI 3 document describing the sidecar API
+func OpenAPI() map[string]interface{} {
+	ref := func(name string) map[string]interface{} {
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	jsonContent := func(schema map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+	}
+	problemContent := map[string]interface{}{googleIDVerifier.ProblemContentType: map[string]interface{}{"schema": ref("Problem")}}
+	stringArray := map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "google-id-verifier sidecar",
+			"version": "1",
+		},
+		"paths": map[string]interface{}{
+			"/verify": map[string]interface{}{
+				"post": map[string]interface{}{
+					"operationId": "verify",
+					"summary":     "Verify a Google-issued ID token",
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content":  jsonContent(ref("VerifyRequest")),
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Valid token", "content": jsonContent(ref("VerifyResponse"))},
+						"400": map[string]interface{}{"description": "Malformed request", "content": problemContent},
+						"401": map[string]interface{}{"description": "Invalid token", "content": problemContent},
+						"403": map[string]interface{}{"description": "Valid token of a user not allowed", "content": problemContent},
+						"405": map[string]interface{}{"description": "Method other than POST", "content": problemContent},
+						"429": map[string]interface{}{"description": "Too many verifications in flight", "content": problemContent},
+					},
+				},
+			},
+			"/health": map[string]interface{}{
+				"get": map[string]interface{}{
+					"operationId": "health",
+					"summary":     "Liveness check",
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "Serving", "content": jsonContent(map[string]interface{}{
+							"type":       "object",
+							"properties": map[string]interface{}{"status": map[string]interface{}{"type": "string"}},
+						})},
+					},
+				},
+			},
+			"/openapi.json": map[string]interface{}{
+				"get": map[string]interface{}{
+					"operationId": "openapi",
+					"summary":     "This document",
+					"responses":   map[string]interface{}{"200": map[string]interface{}{"description": "OpenAPI document"}},
+				},
+			},
+		},
+		"components": map[string]interface{}{
+			"schemas": map[string]interface{}{
+				"VerifyRequest": map[string]interface{}{
+					"type":     "object",
+					"required": []string{"token"},
+					"properties": map[string]interface{}{
+						"token":    map[string]interface{}{"type": "string"},
+						"audience": stringArray,
+					},
+				},
+				"VerifyResponse": map[string]interface{}{
+					"type":     "object",
+					"required": []string{"valid"},
+					"properties": map[string]interface{}{
+						"valid":  map[string]interface{}{"type": "boolean"},
+						"claims": ref("ClaimSet"),
+					},
+				},
+				"Problem": map[string]interface{}{
+					"description": "RFC 7807 problem details",
+					"type":        "object",
+					"required":    []string{"type", "title", "status", "code"},
+					"properties": map[string]interface{}{
+						"type":   map[string]interface{}{"type": "string", "format": "uri"},
+						"title":  map[string]interface{}{"type": "string"},
+						"status": map[string]interface{}{"type": "integer"},
+						"code": map[string]interface{}{"type": "string", "enum": []string{
+							string(googleIDVerifier.ReasonMalformed), string(googleIDVerifier.ReasonExpired),
+							string(googleIDVerifier.ReasonNotYetValid), string(googleIDVerifier.ReasonWrongAudience),
+							string(googleIDVerifier.ReasonNotAllowed), string(googleIDVerifier.ReasonInvalid),
+							"invalid_request", "method_not_allowed", "too_many_requests",
+						}},
+						"detail": map[string]interface{}{"type": "string"},
+					},
+				},
+				"ClaimSet": map[string]interface{}{
+					"type":                 "object",
+					"additionalProperties": true,
+					"properties": map[string]interface{}{
+						"iss":            map[string]interface{}{"type": "string"},
+						"sub":            map[string]interface{}{"type": "string"},
+						"aud":            map[string]interface{}{"oneOf": []interface{}{map[string]interface{}{"type": "string"}, stringArray}},
+						"iat":            map[string]interface{}{"type": "integer", "format": "int64"},
+						"exp":            map[string]interface{}{"type": "integer", "format": "int64"},
+						"email":          map[string]interface{}{"type": "string"},
+						"email_verified": map[string]interface{}{"type": "boolean"},
+						"name":           map[string]interface{}{"type": "string"},
+						"picture":        map[string]interface{}{"type": "string"},
+						"given_name":     map[string]interface{}{"type": "string"},
+						"family_name":    map[string]interface{}{"type": "string"},
+						"locale":         map[string]interface{}{"type": "string"},
+						"hd":             map[string]interface{}{"type": "string"},
+					},
+				},
+			},
+		},
+	}
+}
+
+// OpenAPIHandler serves the OpenAPI document as JSON
+func OpenAPIHandler() http.Handler {
+	doc := OpenAPI()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, doc)
+	})
+}
@@ -0,0 +1,189 @@
+// Package sidecar exposes token verification over a local Unix socket, so applications written in
+// other languages on the same host or pod can share one verifier and its warm cert cache.
+//
+// The API is JSON over HTTP:
+//
+//	POST /verify  {"token": "...", "audience": ["client-id"]}
+//	              200 {"valid": true, "claims": {...}}
+//	              401 application/problem+json {"type": "urn:google-id-verifier:rejection:expired", "title": "Expired token",
+//	                  "status": 401, "code": "expired", "detail": "..."}, or 403 for users not allowed,
+//	                  with the client-safe reasons of googleIDVerifier.ClientReason, the full error going only to WithFailureLogger
+//	              400, 405 and 429, when over the WithMaxInFlight limit, are problem details too
+//	GET  /health  200 {"status": "ok"}
+//	GET  /openapi.json  the OpenAPI 3 document of this API
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+)
+
+// Verifier verifies an ID token for the given audiences
+type Verifier interface {
+	VerifyIDToken(idToken string, audience ...string) (*googleIDVerifier.ClaimSet, error)
+}
+
+// ContextVerifier is a Verifier honoring the context of the request, such as googleIDVerifier.CertsVerifier.
+// Verifiers implementing it stop waiting for their keys when the request is cancelled.
+type ContextVerifier interface {
+	VerifyIDTokenContext(ctx context.Context, idToken string, audience ...string) (*googleIDVerifier.ClaimSet, error)
+}
+
+func verify(ctx context.Context, v Verifier, idToken string, audience []string) (*googleIDVerifier.ClaimSet, error) {
+	if cv, ok := v.(ContextVerifier); ok {
+		return cv.VerifyIDTokenContext(ctx, idToken, audience...)
+	}
+	return v.VerifyIDToken(idToken, audience...)
+}
+
+// VerifyRequest is the body of a /verify call
+type VerifyRequest struct {
+	Token    string   `json:"token"`
+	Audience []string `json:"audience,omitempty"`
+}
+
+// VerifyResponse is the body returned by /verify for valid tokens. Rejected tokens and requests get
+// a googleIDVerifier.Problem instead, whose Code is a googleIDVerifier.RejectionReason for tokens.
+type VerifyResponse struct {
+	Valid  bool                       `json:"valid"`
+	Claims *googleIDVerifier.ClaimSet `json:"claims,omitempty"`
+}
+
+// Option configures the sidecar handler
+type Option func(*options)
+
+type options struct {
+	failureLogger *googleIDVerifier.FailureLogger
+	slots         chan struct{}
+	queueTimeout  time.Duration
+}
+
+// WithFailureLogger logs verification failures through the given rate-limited logger
+func WithFailureLogger(l *googleIDVerifier.FailureLogger) Option {
+	return func(o *options) {
+		o.failureLogger = l
+	}
+}
+
+// WithMaxInFlight limits concurrent verifications to max. Requests over the limit wait up to
+// queueTimeout for a slot, then get a 429 so a spike degrades gracefully instead of exhausting CPU.
+// A max of 0 or less means no limit.
+func WithMaxInFlight(max int, queueTimeout time.Duration) Option {
+	return func(o *options) {
+		o.slots = nil
+		if max > 0 {
+			o.slots = make(chan struct{}, max)
+		}
+		o.queueTimeout = queueTimeout
+	}
+}
+
+// NewHandler returns the sidecar HTTP API backed by the given verifier
+func NewHandler(v Verifier, opts ...Option) http.Handler {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/verify", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeProblem(w, http.StatusMethodNotAllowed, "method_not_allowed")
+			return
+		}
+		if o.slots != nil {
+			if !o.acquire(r.Context()) {
+				w.Header().Set("Retry-After", "1")
+				writeProblem(w, http.StatusTooManyRequests, "too_many_requests")
+				return
+			}
+			defer func() { <-o.slots }()
+		}
+		req := VerifyRequest{}
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil || req.Token == "" {
+			writeProblem(w, http.StatusBadRequest, "invalid_request")
+			return
+		}
+		claimSet, err := verify(r.Context(), v, req.Token, req.Audience)
+		if err != nil {
+			if o.failureLogger != nil {
+				o.failureLogger.Log(err)
+			}
+			googleIDVerifier.WriteRejection(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, VerifyResponse{Valid: true, Claims: claimSet})
+	})
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.Handle("/openapi.json", OpenAPIHandler())
+	return mux
+}
+
+// acquire waits for a verification slot until the queue timeout or the request is done
+func (o *options) acquire(ctx context.Context) bool {
+	select {
+	case o.slots <- struct{}{}:
+		return true
+	default:
+	}
+	timer := time.NewTimer(o.queueTimeout)
+	defer timer.Stop()
+	select {
+	case o.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// ListenAndServe serves handler on a Unix socket at socketPath until ctx is done.
+// A stale socket file left at socketPath is removed first.
+func ListenAndServe(ctx context.Context, socketPath string, handler http.Handler) error {
+	err := os.Remove(socketPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(socketPath)
+
+	srv := &http.Server{Handler: handler}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Shutdown(context.Background())
+	}()
+	err = srv.Serve(l)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// writeProblem writes the problem details of a request rejected before verifying its token
+func writeProblem(w http.ResponseWriter, status int, code string) {
+	googleIDVerifier.WriteProblem(w, &googleIDVerifier.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Code:   code,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
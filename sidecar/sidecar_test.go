@@ -0,0 +1,163 @@
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	googleIDVerifier "github.com/fafg/google-id-verifier"
+	"github.com/fafg/google-id-verifier/idptest"
+)
+
+type certsVerifier struct {
+	idp *idptest.IDP
+}
+
+func (v *certsVerifier) VerifyIDToken(idToken string, audience ...string) (*googleIDVerifier.ClaimSet, error) {
+	return googleIDVerifier.VerifySignedJWTWithCerts(idToken, v.idp.Certs(), audience,
+		googleIDVerifier.Issuers, googleIDVerifier.MaxTokenLifetime)
+}
+
+func TestSidecar(t *testing.T) {
+	idp, err := idptest.New("client-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idp.Close()
+
+	failures := googleIDVerifier.NewFailureLogger(func(string, ...interface{}) {})
+	socket := filepath.Join(t.TempDir(), "sidecar.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- ListenAndServe(ctx, socket, NewHandler(&certsVerifier{idp: idp}, WithFailureLogger(failures)))
+	}()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	verify := func(req VerifyRequest) (int, VerifyResponse) {
+		body, _ := json.Marshal(req)
+		var resp *http.Response
+		for i := 0; i < 50; i++ {
+			resp, err = client.Post("http://sidecar/verify", "application/json", bytes.NewReader(body))
+			if err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		res := VerifyResponse{}
+		_ = json.NewDecoder(resp.Body).Decode(&res)
+		return resp.StatusCode, res
+	}
+
+	token, _ := idp.Token(nil)
+	status, res := verify(VerifyRequest{Token: token, Audience: []string{"client-id"}})
+	if status != http.StatusOK || !res.Valid || res.Claims.Email != "user@example.com" {
+		t.Errorf("Expect valid token, got %d %+v", status, res)
+	}
+
+	body, _ := json.Marshal(VerifyRequest{Token: token, Audience: []string{"other"}})
+	resp, err := client.Post("http://sidecar/verify", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	problem := &googleIDVerifier.Problem{}
+	_ = json.NewDecoder(resp.Body).Decode(problem)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("Content-Type") != googleIDVerifier.ProblemContentType ||
+		problem.Code != string(googleIDVerifier.ReasonWrongAudience) || strings.Contains(problem.Detail, "client-id") {
+		t.Errorf("Expect rejected token with a client-safe problem, got %d %+v", resp.StatusCode, problem)
+	}
+
+	if failures.Counts()["wrong aud"] != 1 {
+		t.Errorf("Expect failure to be logged, got %v", failures.Counts())
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewHandler(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	doc := map[string]interface{}{}
+	err := json.NewDecoder(rec.Body).Decode(&doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, _ := doc["paths"].(map[string]interface{})
+	for _, path := range []string{"/verify", "/health"} {
+		if _, ok := paths[path]; !ok {
+			t.Errorf("Expect %s to be documented", path)
+		}
+	}
+}
+
+type blockingVerifier struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (v *blockingVerifier) VerifyIDToken(string, ...string) (*googleIDVerifier.ClaimSet, error) {
+	v.started <- struct{}{}
+	<-v.release
+	return &googleIDVerifier.ClaimSet{}, nil
+}
+
+func TestMaxInFlight(t *testing.T) {
+	v := &blockingVerifier{started: make(chan struct{}), release: make(chan struct{})}
+	h := NewHandler(v, WithMaxInFlight(1, 10*time.Millisecond))
+	post := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/verify", bytes.NewBufferString(`{"token": "t"}`)))
+		return rec
+	}
+
+	done := make(chan int)
+	go func() { done <- post().Code }()
+	<-v.started
+
+	if rec := post(); rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Expect 429 while saturated, got %d", rec.Code)
+	}
+	close(v.release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("Expect first request to succeed, got %d", code)
+	}
+}
+
+func TestMaxInFlightUnlimited(t *testing.T) {
+	v := &blockingVerifier{started: make(chan struct{}), release: make(chan struct{})}
+	h := NewHandler(v, WithMaxInFlight(0, time.Millisecond))
+	done := make(chan int)
+	for i := 0; i < 2; i++ {
+		go func() {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/verify", bytes.NewBufferString(`{"token": "t"}`)))
+			done <- rec.Code
+		}()
+		<-v.started
+	}
+	close(v.release)
+	for i := 0; i < 2; i++ {
+		if code := <-done; code != http.StatusOK {
+			t.Errorf("Expect no limit with a max of 0, got %d", code)
+		}
+	}
+}
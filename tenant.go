@@ -0,0 +1,39 @@
+package googleIDVerifier
+
+// Tenant is the metadata of a tenant owning an OAuth client ID
+type Tenant struct {
+	ID       string
+	Name     string
+	Metadata map[string]string
+}
+
+// TenantsByAudience maps OAuth client IDs (aud claims) to their tenant
+type TenantsByAudience map[string]*Tenant
+
+// Audiences returns every mapped client ID, to be used as allowed audiences
+func (t TenantsByAudience) Audiences() []string {
+	auds := make([]string, 0, len(t))
+	for aud := range t {
+		auds = append(auds, aud)
+	}
+	return auds
+}
+
+// VerifyIDTokenTenant verifies the token like VerifyIDToken and returns the tenant of its audience.
+// When no audience is given, every client ID of tenants is allowed. For tokens with several audiences
+// the tenant is the one of the first allowed audience.
+func (v *CertsVerifier) VerifyIDTokenTenant(idToken string, tenants TenantsByAudience, audience ...string) (*ClaimSet, *Tenant, error) {
+	if len(audience) == 0 {
+		audience = tenants.Audiences()
+	}
+	claimSet, err := v.VerifyIDToken(idToken, audience...)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, aud := range claimSet.audiences() {
+		if tenant, ok := tenants[aud]; ok && contains(audience, aud) {
+			return claimSet, tenant, nil
+		}
+	}
+	return nil, nil, ErrUnknownTenant
+}
@@ -0,0 +1,62 @@
+package googleIDVerifier
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+)
+
+func TestVerifyIDTokenTenant(t *testing.T) {
+	certs, _ := getTestCerts()
+	defer func(keySet *KeySet) { googleKeySet = keySet }(googleKeySet)
+	googleKeySet = NewKeySet(certs, nil)
+	_, claimSet, _ := parseJWT(validTestToken)
+	nowFn = func() time.Time {
+		return time.Unix(claimSet.Exp, 0)
+	}
+	defer func() { nowFn = time.Now }()
+
+	acme := &Tenant{ID: "acme"}
+	tenants := TenantsByAudience{
+		claimSet.Aud:             acme,
+		"other.apps.example.com": {ID: "other"},
+	}
+	v := &CertsVerifier{}
+	_, tenant, err := v.VerifyIDTokenTenant(validTestToken, tenants)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tenant != acme {
+		t.Errorf("Expect acme tenant, got %+v", tenant)
+	}
+
+	_, _, err = v.VerifyIDTokenTenant(validTestToken, TenantsByAudience{"x": acme}, claimSet.Aud)
+	if err != ErrUnknownTenant {
+		t.Errorf("Expect ErrUnknownTenant, got %v", err)
+	}
+}
+
+func TestVerifyIDTokenTenantAudiences(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewStaticVerifier(&Certs{ECKeys: map[string]*ecdsa.PublicKey{"ec": &priv.PublicKey}}, WithAllowedAlgorithms(es256))
+	now := time.Now()
+	token := signES256(t, priv, "ec", map[string]interface{}{
+		"iss": "https://accounts.google.com", "aud": []string{"tenant-b", "tenant-a"}, "sub": "1",
+		"iat": now.Unix(), "exp": now.Add(time.Hour).Unix(),
+	})
+
+	a, b := &Tenant{ID: "a"}, &Tenant{ID: "b"}
+	_, tenant, err := v.VerifyIDTokenTenant(token, TenantsByAudience{"tenant-a": a, "tenant-b": b}, "tenant-a")
+	if err != nil || tenant != a {
+		t.Errorf("Expect the tenant of the verified audience, got %+v %v", tenant, err)
+	}
+	_, tenant, err = v.VerifyIDTokenTenant(token, TenantsByAudience{"tenant-a": a})
+	if err != nil || tenant != a {
+		t.Errorf("Expect the tenant of the mapped audience, got %+v %v", tenant, err)
+	}
+}
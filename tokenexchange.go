@@ -0,0 +1,125 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	grantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	// TokenTypeIDToken is the RFC 8693 token type of an OIDC ID token
+	TokenTypeIDToken = "urn:ietf:params:oauth:token-type:id_token"
+	// TokenTypeAccessToken is the RFC 8693 token type of an OAuth 2.0 access token
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+	// TokenTypeJWT is the RFC 8693 token type of a JWT
+	TokenTypeJWT = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// TokenExchanger exchanges ID tokens for downstream tokens at an RFC 8693 security token service
+type TokenExchanger struct {
+	// Endpoint is the STS token endpoint
+	Endpoint string
+	// Audience, Resource, Scope and RequestedTokenType are sent when not empty
+	Audience           string
+	Resource           string
+	Scope              []string
+	RequestedTokenType string
+	// ClientID and ClientSecret authenticate to the STS with HTTP basic auth when set
+	ClientID     string
+	ClientSecret string
+	// Client sends the request, http.DefaultClient when nil
+	Client *http.Client
+}
+
+// ExchangedToken is a successful RFC 8693 token exchange response
+type ExchangedToken struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in,omitempty"`
+	Scope           string `json:"scope,omitempty"`
+	RefreshToken    string `json:"refresh_token,omitempty"`
+}
+
+// TokenExchangeError is an error response of the STS
+type TokenExchangeError struct {
+	StatusCode  int
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *TokenExchangeError) Error() string {
+	if e.Description != "" {
+		return "token exchange failed: " + e.Code + ": " + e.Description
+	}
+	return "token exchange failed: " + e.Code
+}
+
+// Exchange sends the ID token as subject_token to the STS and returns the issued token
+func (e *TokenExchanger) Exchange(ctx context.Context, idToken string) (*ExchangedToken, error) {
+	form := url.Values{
+		"grant_type":         {grantTypeTokenExchange},
+		"subject_token":      {idToken},
+		"subject_token_type": {TokenTypeIDToken},
+	}
+	for k, v := range map[string]string{
+		"audience":             e.Audience,
+		"resource":             e.Resource,
+		"scope":                strings.Join(e.Scope, " "),
+		"requested_token_type": e.RequestedTokenType,
+	} {
+		if v != "" {
+			form.Set(k, v)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if e.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(e.ClientID), url.QueryEscape(e.ClientSecret))
+	}
+
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		exchangeErr := &TokenExchangeError{StatusCode: resp.StatusCode}
+		_ = json.NewDecoder(resp.Body).Decode(exchangeErr)
+		return nil, exchangeErr
+	}
+	token := &ExchangedToken{}
+	err = json.NewDecoder(resp.Body).Decode(token)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// VerifyAndExchange verifies the ID token like VerifyIDToken, then exchanges it with the given exchanger
+func (v *CertsVerifier) VerifyAndExchange(ctx context.Context, idToken string, e *TokenExchanger,
+	audience ...string) (*ClaimSet, *ExchangedToken, error) {
+	claimSet, err := v.VerifyIDTokenContext(ctx, idToken, audience...)
+	if err != nil {
+		return nil, nil, err
+	}
+	token, err := e.Exchange(ctx, idToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	return claimSet, token, nil
+}
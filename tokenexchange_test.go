@@ -0,0 +1,43 @@
+package googleIDVerifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTokenExchanger(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		if r.PostFormValue("grant_type") != grantTypeTokenExchange || r.PostFormValue("subject_token_type") != TokenTypeIDToken ||
+			user != "client" || r.PostFormValue("audience") != "internal" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": "invalid_request"}`))
+			return
+		}
+		if r.PostFormValue("subject_token") != validTestToken {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": "invalid_grant", "error_description": "bad subject token"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token": "internal-token", "issued_token_type": "` + TokenTypeAccessToken + `", "token_type": "Bearer"}`))
+	}))
+	defer srv.Close()
+
+	e := &TokenExchanger{Endpoint: srv.URL, Audience: "internal", ClientID: "client", ClientSecret: "secret"}
+	token, err := e.Exchange(context.Background(), validTestToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "internal-token" || token.IssuedTokenType != TokenTypeAccessToken {
+		t.Errorf("Unexpected token %+v", token)
+	}
+
+	_, err = e.Exchange(context.Background(), wrongSigToken)
+	exchangeErr := &TokenExchangeError{}
+	if !errors.As(err, &exchangeErr) || exchangeErr.Code != "invalid_grant" || exchangeErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expect invalid_grant TokenExchangeError, got %v", err)
+	}
+}
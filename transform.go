@@ -0,0 +1,45 @@
+package googleIDVerifier
+
+// ClaimsTransformer maps verified claims into an application identity model, e.g. a *User.
+// It receives the identity built by the previous transformer, nil for the first one,
+// and returns the updated identity.
+type ClaimsTransformer func(claimSet *ClaimSet, identity interface{}) (interface{}, error)
+
+// TransformPipeline runs its registered transformers in order over verified claims
+type TransformPipeline struct {
+	transformers []ClaimsTransformer
+}
+
+// NewTransformPipeline returns a pipeline running the given transformers
+func NewTransformPipeline(transformers ...ClaimsTransformer) *TransformPipeline {
+	return &TransformPipeline{transformers: transformers}
+}
+
+// Register appends a transformer to the pipeline
+func (p *TransformPipeline) Register(t ClaimsTransformer) *TransformPipeline {
+	p.transformers = append(p.transformers, t)
+	return p
+}
+
+// Transform returns the identity built by running every transformer over the claims,
+// stopping at the first error
+func (p *TransformPipeline) Transform(claimSet *ClaimSet) (interface{}, error) {
+	var identity interface{}
+	for _, t := range p.transformers {
+		var err error
+		identity, err = t(claimSet, identity)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return identity, nil
+}
+
+// VerifyIdentity verifies the token like VerifyIDToken and returns the identity built from its claims by the pipeline
+func (v *CertsVerifier) VerifyIdentity(idToken string, pipeline *TransformPipeline, audience ...string) (interface{}, error) {
+	claimSet, err := v.VerifyIDToken(idToken, audience...)
+	if err != nil {
+		return nil, err
+	}
+	return pipeline.Transform(claimSet)
+}
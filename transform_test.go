@@ -0,0 +1,44 @@
+package googleIDVerifier
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testUser struct {
+	ID     string
+	Email  string
+	Domain string
+}
+
+func TestTransformPipeline(t *testing.T) {
+	_, claimSet, _ := parseJWT(validTestToken)
+
+	pipeline := NewTransformPipeline(func(c *ClaimSet, _ interface{}) (interface{}, error) {
+		return &testUser{ID: c.Sub, Email: c.Email, Domain: c.HostedDomain}, nil
+	}).Register(func(c *ClaimSet, identity interface{}) (interface{}, error) {
+		u := identity.(*testUser)
+		if u.Domain == "" {
+			u.Domain = u.Email[strings.Index(u.Email, "@")+1:]
+		}
+		return u, nil
+	})
+
+	identity, err := pipeline.Transform(claimSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := identity.(*testUser)
+	if u.ID != claimSet.Sub || u.Domain != "gmail.com" {
+		t.Errorf("Unexpected identity %+v", u)
+	}
+
+	errRejected := errors.New("rejected")
+	_, err = pipeline.Register(func(*ClaimSet, interface{}) (interface{}, error) {
+		return nil, errRejected
+	}).Transform(claimSet)
+	if err != errRejected {
+		t.Errorf("Expect transformer error, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package googleIDVerifier
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// FailureReason is the machine-readable cause of a verification failure
+type FailureReason string
+
+const (
+	// FailureMalformed is a token which cannot be decoded or misses iat or exp
+	FailureMalformed FailureReason = "malformed"
+	// FailureKeyNotFound is a token signed with an unknown kid
+	FailureKeyNotFound FailureReason = "key_not_found"
+	// FailureBadSignature is a token whose signature does not match its key
+	FailureBadSignature FailureReason = "bad_signature"
+	// FailureExpired is a token used after its expiration time
+	FailureExpired FailureReason = "expired"
+	// FailureNotYetValid is a token used before its issue time
+	FailureNotYetValid FailureReason = "not_yet_valid"
+	// FailureLifetimeTooLong is a token expiring further than the max token lifetime
+	FailureLifetimeTooLong FailureReason = "lifetime_too_long"
+	// FailureWrongIssuer is a token from an issuer which is not accepted
+	FailureWrongIssuer FailureReason = "wrong_issuer"
+	// FailureWrongAudience is a token issued for another client
+	FailureWrongAudience FailureReason = "wrong_audience"
+	// FailureClaimRejected is a token rejected by the additional claim checks
+	FailureClaimRejected FailureReason = "claim_rejected"
+	// FailureOther covers every other failure, e.g. the keys could not be fetched
+	FailureOther FailureReason = "other"
+)
+
+// VerificationError is the error returned by CertsVerifier. It wraps the underlying error,
+// so errors.Is(err, ErrWrongAudience) and the like keep working, and has the same message.
+type VerificationError struct {
+	Reason FailureReason
+	// Claim is the name of the offending claim, if any
+	Claim string
+	// Value is the value of Claim in the token
+	Value interface{}
+	Err   error
+}
+
+func (e *VerificationError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+var failureReasons = []struct {
+	err    error
+	reason FailureReason
+	claim  string
+}{
+	{ErrInvalidToken, FailureMalformed, ""},
+	{ErrNoIssueTimeInToken, FailureMalformed, "iat"},
+	{ErrNoExpirationTimeInToken, FailureMalformed, "exp"},
+	{ErrPublicKeyNotFound, FailureKeyNotFound, ""},
+	{ErrWrongSignature, FailureBadSignature, ""},
+	{ErrUnsupportedAlgorithm, FailureBadSignature, ""},
+	{ErrTokenUsedTooLate, FailureExpired, "exp"},
+	{ErrTokenUsedTooEarly, FailureNotYetValid, "iat"},
+	{ErrExpirationTimeTooFarInFuture, FailureLifetimeTooLong, "exp"},
+	{ErrWrongIssuer, FailureWrongIssuer, "iss"},
+	{ErrWrongAudience, FailureWrongAudience, "aud"},
+	{ErrHostedDomainNotAllowed, FailureClaimRejected, "hd"},
+	{ErrEmailNotVerified, FailureClaimRejected, "email_verified"},
+	{ErrEmailNotAllowed, FailureClaimRejected, "email"},
+	{ErrMissingClaim, FailureClaimRejected, ""},
+	{ErrWrongClaimType, FailureClaimRejected, ""},
+	{ErrEntitlementNotAllowed, FailureClaimRejected, ""},
+	{ErrWrongNonce, FailureClaimRejected, "nonce"},
+	{ErrAuthorizedPartyNotAllowed, FailureClaimRejected, "azp"},
+	{ErrWrongAccessTokenHash, FailureClaimRejected, "at_hash"},
+}
+
+// FailureReasonOf returns the reason of a verification error, including the plain errors returned by KeySet
+func FailureReasonOf(err error) FailureReason {
+	var verr *VerificationError
+	if errors.As(err, &verr) {
+		return verr.Reason
+	}
+	reason, _ := classifyFailure(err)
+	return reason
+}
+
+func classifyFailure(err error) (FailureReason, string) {
+	var corrupt base64.CorruptInputError
+	var syntax *json.SyntaxError
+	var unmarshalType *json.UnmarshalTypeError
+	if errors.As(err, &corrupt) || errors.As(err, &syntax) || errors.As(err, &unmarshalType) {
+		return FailureMalformed, ""
+	}
+	for _, f := range failureReasons {
+		if errors.Is(err, f.err) {
+			return f.reason, f.claim
+		}
+	}
+	return FailureOther, ""
+}
+
+// newVerificationError wraps err with its reason and the value of the offending claim in the token
+func newVerificationError(token string, err error) *VerificationError {
+	var verr *VerificationError
+	if errors.As(err, &verr) {
+		return verr
+	}
+	reason, claim := classifyFailure(err)
+	verr = &VerificationError{Reason: reason, Claim: claim, Err: err}
+	if claim != "" {
+		if claims, decodeErr := decodeRawClaims(token); decodeErr == nil {
+			verr.Value = claims[claim]
+		}
+	}
+	return verr
+}
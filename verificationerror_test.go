@@ -0,0 +1,37 @@
+package googleIDVerifier
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerificationError(t *testing.T) {
+	certs, _ := getTestCerts()
+	_, claimSet, _ := parseJWT(validTestToken)
+	v := NewVerifier(WithKeySet(NewKeySet(certs, nil)))
+
+	_, err := v.VerifyIDToken(validTestToken, claimSet.Aud)
+	verr := &VerificationError{}
+	if !errors.As(err, &verr) || verr.Reason != FailureExpired || verr.Claim != "exp" || verr.Value != float64(claimSet.Exp) {
+		t.Errorf("Expect expired VerificationError, got %#v", err)
+	}
+	if !errors.Is(err, ErrTokenUsedTooLate) || err.Error() != ErrTokenUsedTooLate.Error() {
+		t.Errorf("Expect VerificationError to wrap ErrTokenUsedTooLate, got %v", err)
+	}
+
+	nowFn = func() time.Time { return time.Unix(claimSet.Exp, 0) }
+	defer func() { nowFn = time.Now }()
+	_, err = v.VerifyIDToken(validTestToken, "other")
+	if !errors.As(err, &verr) || verr.Reason != FailureWrongAudience || verr.Value != claimSet.Aud || !errors.Is(err, ErrWrongAudience) {
+		t.Errorf("Expect wrong audience VerificationError, got %#v", err)
+	}
+
+	_, err = VerifySignedJWTWithCerts(wrongSigToken, certs, []string{claimSet.Aud}, Issuers, MaxTokenLifetime)
+	if FailureReasonOf(err) != FailureBadSignature || FailureReasonOf(errors.New("boom")) != FailureOther {
+		t.Errorf("Unexpected reason %s for %v", FailureReasonOf(err), err)
+	}
+	if _, err = v.VerifyIDToken("a.b"); FailureReasonOf(err) != FailureMalformed {
+		t.Error("Expect malformed token")
+	}
+}
@@ -0,0 +1,149 @@
+// Package verifiedaccess verifies Chrome Verified Access challenge responses, attesting that a request
+// comes from a managed ChromeOS device or Chrome browser.
+//
+// The attestation itself is checked by Google's Verified Access API, so the Client needs an HTTP client
+// authorized with the https://www.googleapis.com/auth/verifiedaccess scope, e.g. from golang.org/x/oauth2/google:
+//
+//	c := &verifiedaccess.Client{HTTPClient: oauthClient}
+//	challenge, _ := c.GenerateChallenge(ctx)
+//	// send challenge to the device, which answers with chrome.enterprise.platformKeys.challengeKey
+//	verdict, err := c.Verify(ctx, challengeResponse, "")
+package verifiedaccess
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const defaultBaseURL = "https://verifiedaccess.googleapis.com/v2"
+
+// Key trust levels reported in Verdict.KeyTrustLevel
+const (
+	ChromeOSVerifiedMode  = "CHROME_OS_VERIFIED_MODE"
+	ChromeOSDeveloperMode = "CHROME_OS_DEVELOPER_MODE"
+	ChromeBrowserHWKey    = "CHROME_BROWSER_HW_KEY"
+	ChromeBrowserOSKey    = "CHROME_BROWSER_OS_KEY"
+	ChromeBrowserNoKey    = "CHROME_BROWSER_NO_KEY"
+)
+
+// ErrNotVerifiedMode is returned by Verdict.RequireVerifiedMode for devices not in ChromeOS verified mode
+var ErrNotVerifiedMode = errors.New("Device not in verified mode")
+
+// Client calls the Verified Access API
+type Client struct {
+	// HTTPClient must add OAuth2 credentials for the verifiedaccess scope
+	HTTPClient *http.Client
+	// BaseURL defaults to the v2 Verified Access API
+	BaseURL string
+}
+
+// Verdict is the device and user attestation of a verified challenge response
+type Verdict struct {
+	// DevicePermanentID is the device serial number, for ChromeOS devices
+	DevicePermanentID string `json:"devicePermanentId,omitempty"`
+	// AttestedDeviceID is the device ID as registered in the Admin console
+	AttestedDeviceID string `json:"attestedDeviceId,omitempty"`
+	// CustomerID is the Google Workspace customer owning the device
+	CustomerID string `json:"customerId,omitempty"`
+	// ProfileCustomerID is the customer owning the Chrome profile, for user-level attestation
+	ProfileCustomerID string `json:"profileCustomerId,omitempty"`
+	// VirtualDeviceID identifies the Chrome browser, for browser attestation
+	VirtualDeviceID string `json:"virtualDeviceId,omitempty"`
+	// DeviceEnrollmentDomain is the domain the device is enrolled in
+	DeviceEnrollmentDomain string `json:"deviceEnrollmentDomain,omitempty"`
+	// SignedPublicKeyAndChallenge is the certified public key of the device, when one was requested
+	SignedPublicKeyAndChallenge string `json:"signedPublicKeyAndChallenge,omitempty"`
+	// KeyTrustLevel tells how much the device key can be trusted, see the Chrome* constants
+	KeyTrustLevel string `json:"keyTrustLevel,omitempty"`
+	// ProfileKeyTrustLevel is KeyTrustLevel for the profile key
+	ProfileKeyTrustLevel string `json:"profileKeyTrustLevel,omitempty"`
+	// DeviceSignals are the raw device signals, e.g. OS version or screen lock state
+	DeviceSignals map[string]interface{} `json:"deviceSignals,omitempty"`
+}
+
+// RequireVerifiedMode returns ErrNotVerifiedMode unless the device key is from a ChromeOS device in verified mode
+func (v *Verdict) RequireVerifiedMode() error {
+	if v.KeyTrustLevel != ChromeOSVerifiedMode {
+		return fmt.Errorf("%w: %s", ErrNotVerifiedMode, v.KeyTrustLevel)
+	}
+	return nil
+}
+
+// APIError is a non 2xx response of the Verified Access API
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("verified access: %d %s", e.StatusCode, e.Message)
+}
+
+// GenerateChallenge returns a new base64 encoded challenge to send to the device
+func (c *Client) GenerateChallenge(ctx context.Context) (string, error) {
+	res := struct {
+		Challenge string `json:"challenge"`
+	}{}
+	err := c.post(ctx, "/challenge:generate", struct{}{}, &res)
+	if err != nil {
+		return "", err
+	}
+	return res.Challenge, nil
+}
+
+// Verify checks the base64 encoded challenge response of the device. expectedIdentity, if not empty,
+// is the device or user identity the response must come from.
+func (c *Client) Verify(ctx context.Context, challengeResponse string, expectedIdentity string) (*Verdict, error) {
+	req := struct {
+		ChallengeResponse string `json:"challengeResponse"`
+		ExpectedIdentity  string `json:"expectedIdentity,omitempty"`
+	}{challengeResponse, expectedIdentity}
+	verdict := &Verdict{}
+	err := c.post(ctx, "/challenge:verify", req, verdict)
+	if err != nil {
+		return nil, err
+	}
+	return verdict, nil
+}
+
+func (c *Client) post(ctx context.Context, path string, body interface{}, out interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	req, err := http.NewRequest(http.MethodPost, baseURL+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		apiErr := struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}{}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		return &APIError{StatusCode: resp.StatusCode, Message: apiErr.Error.Message}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
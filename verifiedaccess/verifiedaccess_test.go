@@ -0,0 +1,53 @@
+package verifiedaccess
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/challenge:generate":
+			_, _ = w.Write([]byte(`{"challenge": "Y2hhbGxlbmdl"}`))
+		case "/challenge:verify":
+			req := map[string]string{}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if req["challengeResponse"] != "good" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error": {"message": "invalid challenge response"}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"devicePermanentId": "SERIAL", "customerId": "C01", "keyTrustLevel": "CHROME_OS_DEVELOPER_MODE"}`))
+		}
+	}))
+	defer srv.Close()
+	c := &Client{BaseURL: srv.URL}
+	ctx := context.Background()
+
+	challenge, err := c.GenerateChallenge(ctx)
+	if err != nil || challenge != "Y2hhbGxlbmdl" {
+		t.Fatalf("Unexpected challenge %q: %v", challenge, err)
+	}
+
+	verdict, err := c.Verify(ctx, "good", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verdict.DevicePermanentID != "SERIAL" || verdict.CustomerID != "C01" {
+		t.Errorf("Unexpected verdict %+v", verdict)
+	}
+	if err := verdict.RequireVerifiedMode(); !errors.Is(err, ErrNotVerifiedMode) {
+		t.Errorf("Expect ErrNotVerifiedMode for developer mode, got %v", err)
+	}
+
+	_, err = c.Verify(ctx, "bad", "")
+	apiErr := &APIError{}
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Message != "invalid challenge response" {
+		t.Errorf("Expect APIError, got %v", err)
+	}
+}
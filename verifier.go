@@ -1,7 +1,12 @@
 package googleIDVerifier
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/oauth2/jws"
@@ -19,6 +24,11 @@ var (
 		"accounts.google.com",
 		"https://accounts.google.com",
 	}
+
+	// AllowedAlgorithms are the accepted token algorithms: RS256, the one of the Google ID tokens. Tokens
+	// with any other alg, such as none or HS256, are rejected with ErrUnsupportedAlgorithm. Verifiers of
+	// ES256 tokens opt in with WithAllowedAlgorithms, as the IAP, Firebase and OIDC verifiers do.
+	AllowedAlgorithms = []string{rs256}
 )
 
 // TokenVerifier has a method to verify a Google-issued OAuth2 token ID
@@ -27,72 +37,390 @@ type TokenVerifier interface {
 	VerifyIDToken(idToken string, audience ...string) error
 }
 
+// ClaimsVerifier verifies a token for the given audiences, or else its default ones, and returns its claims
+type ClaimsVerifier interface {
+	VerifyIDToken(idToken string, audience ...string) (*ClaimSet, error)
+}
+
+// PayloadValidator validates the raw JSON claims payload of a token, e.g. against a JSON Schema
+type PayloadValidator interface {
+	ValidatePayload(payload []byte) error
+}
+
 // CertsVerifier implements Verifier by fetching once in a while the Google certs and validating the ID tokens locally
 type CertsVerifier struct {
 	DefaultAudience []string
+	// AudienceMatchers accept the tokens with an audience for which one of them returns true, besides
+	// the ones with an audience listed, see AudiencePrefix and AudienceGlob
+	AudienceMatchers []func(aud string) bool
+
+	// MaxTokenLifetime overrides the package MaxTokenLifetime when not zero
+	MaxTokenLifetime time.Duration
+	// ClockSkew overrides the package ClockSkew when not zero
+	ClockSkew time.Duration
+
+	// KeySet verifies the token signatures, the Google federated sign-on certs when nil
+	KeySet *KeySet
+	// HTTPClient, if set, fetches the Google certs when KeySet is nil. Otherwise a client
+	// with a 10 seconds timeout is used. It is read on first use.
+	HTTPClient *http.Client
+	// KeyFetchAuth, if set, authenticates the requests to private key endpoints: the ones of WithCertsURL
+	// and NewOIDCVerifier, and the discovery document. The public Google endpoints are fetched without it.
+	KeyFetchAuth RequestAuth
+	// Issuers overrides the package Issuers when not empty
+	Issuers []string
+	// Algorithms overrides the package AllowedAlgorithms when not empty
+	Algorithms []string
+	// PinnedKeyIDs, if set, are the only kids accepted, so a compromised key endpoint cannot introduce
+	// new keys. The keys of the pinned kids are still the ones served: pin the keys themselves with
+	// NewStaticVerifier.
+	PinnedKeyIDs []string
+
+	// Clock, if set, tells the current time when checking the token times, e.g. to replay old tokens
+	Clock func() time.Time
+	// ReferenceClock, if set, is a trusted time source SelfTest checks Clock against, e.g. HTTPDateClock
+	ReferenceClock func(ctx context.Context) (time.Time, error)
+	// SignatureFirst restores the original check order, verifying the signature before the claims.
+	// By default the cheap claim checks run first so garbage tokens are rejected before any RSA operation.
+	SignatureFirst bool
+	// FastDecode enables a decoding path which splits the token without allocating and decodes its
+	// segments into pooled buffers. It is opt-in while it proves itself in the fuzz tests, which check
+	// it against the default path.
+	FastDecode bool
+
+	// AllowExpired tolerates expired tokens which pass every other check, returning their claims
+	// along with ErrTokenExpired, e.g. for log replay or a grace period. Provisioner, RevocationSampler
+	// and OnExpiringSoon are skipped for them.
+	AllowExpired bool
+
+	// OnKeyMiss, if set, is called when a token references a kid missing from the cached certs,
+	// with found telling whether the kid was present after refetching them
+	OnKeyMiss func(kid string, found bool)
+
+	// RequiredClaims are checked after the standard checks succeed
+	RequiredClaims []ClaimRequirement
+
+	// Assert, if set, adds checks to the assertion chain run after the standard checks succeed, e.g.
+	//	func(c *ClaimAssertion) *ClaimAssertion { return c.RequireHostedDomain("example.com") }
+	Assert func(c *ClaimAssertion) *ClaimAssertion
+
+	// ClaimValidators are run after the standard checks succeed, see WithClaimValidator
+	ClaimValidators []func(claimSet *ClaimSet) error
+
+	// PayloadValidator, if set, is run on the claims payload after the standard checks succeed
+	PayloadValidator PayloadValidator
+
+	// Provisioner, if set, is run once per new subject after all checks succeed
+	Provisioner *Provisioner
+
+	// RevocationSampler, if set, cross-checks a sample of the verified tokens with Google's tokeninfo endpoint
+	RevocationSampler *RevocationSampler
+
+	// Anomalies, if set, observes verification failures
+	Anomalies *AnomalyTracker
+
+	// Metrics, if set, receives verification counts and latencies
+	Metrics Metrics
+
+	// Logger, if set, logs the verification failures
+	Logger Logger
+
+	// OnExpiringSoon, if set, is called for valid tokens expiring within ExpiringSoon,
+	// so callers can ask clients to refresh their token before it gets rejected
+	OnExpiringSoon func(claimSet *ClaimSet, remaining time.Duration)
+	ExpiringSoon   time.Duration
+
+	clientKeySetOnce sync.Once
+	clientKeySet     *KeySet
 }
 
 // VerifyIDToken checks the validity of a given Google-issued OAuth2 token ID
 func (v *CertsVerifier) VerifyIDToken(idToken string, audience ...string) (*ClaimSet, error) {
-	certs, err := getFederatedSignOnCerts()
-	if err != nil {
-		return nil, err
+	return v.VerifyIDTokenWithIssuersContext(context.Background(), idToken, v.issuers(), audience...)
+}
+
+// VerifyIDTokenContext is VerifyIDToken, giving up on fetching the Google certs when ctx is done
+func (v *CertsVerifier) VerifyIDTokenContext(ctx context.Context, idToken string, audience ...string) (*ClaimSet, error) {
+	return v.VerifyIDTokenWithIssuersContext(ctx, idToken, v.issuers(), audience...)
+}
+
+func (v *CertsVerifier) issuers() []string {
+	if len(v.Issuers) > 0 {
+		return v.Issuers
 	}
-	if len(audience) == 0 {
-		audience = v.DefaultAudience
+	return Issuers
+}
+
+// StartKeyRefresh refreshes the keys of the verifier in the background until ctx is done, see KeySet.StartRefresh
+func (v *CertsVerifier) StartKeyRefresh(ctx context.Context) error {
+	return v.keySet().StartRefresh(ctx)
+}
+
+func (v *CertsVerifier) maxTokenLifetime() time.Duration {
+	if v.MaxTokenLifetime != 0 {
+		return v.MaxTokenLifetime
 	}
-	return VerifySignedJWTWithCerts(idToken, certs, audience, Issuers, MaxTokenLifetime)
+	return MaxTokenLifetime
 }
 
-// VerifySignedJWTWithCerts is golang port of OAuth2Client.prototype.verifySignedJwtWithCerts
-func VerifySignedJWTWithCerts(token string, certs *Certs, allowedAuds []string,
-	issuers []string, maxExpiry time.Duration) (*ClaimSet, error) {
+func (v *CertsVerifier) clockSkew() time.Duration {
+	if v.ClockSkew != 0 {
+		return v.ClockSkew
+	}
+	return ClockSkew
+}
 
-	header, claimSet, err := parseJWT(token)
-	if err != nil {
-		return nil, err
+func (v *CertsVerifier) now() time.Time {
+	if v.Clock != nil {
+		return v.Clock()
 	}
+	return nowFn()
+}
 
-	err = basicChecks(token, certs, header, claimSet, maxExpiry)
-	if err != nil {
-		return nil, err
+func (v *CertsVerifier) keySet() *KeySet {
+	if v.KeySet != nil {
+		return v.KeySet
+	}
+	if v.HTTPClient == nil {
+		return googleKeySet
 	}
+	v.clientKeySetOnce.Do(func() {
+		v.clientKeySet = NewGoogleKeySetWithClient(v.HTTPClient)
+	})
+	return v.clientKeySet
+}
 
-	err = checkIssuer(claimSet, issuers)
+// VerifyIDTokenWithIssuers is VerifyIDToken accepting only the given issuers instead of the verifier Issuers,
+// for code paths serving callers from another trust domain
+func (v *CertsVerifier) VerifyIDTokenWithIssuers(idToken string, issuers []string, audience ...string) (*ClaimSet, error) {
+	return v.VerifyIDTokenWithIssuersContext(context.Background(), idToken, issuers, audience...)
+}
+
+// VerifyIDTokenWithIssuersContext is VerifyIDTokenWithIssuers, giving up on fetching the Google certs when ctx is done
+func (v *CertsVerifier) VerifyIDTokenWithIssuersContext(ctx context.Context, idToken string, issuers []string,
+	audience ...string) (*ClaimSet, error) {
+	start := time.Now()
+	claimSet, err := v.verifyIDToken(ctx, idToken, issuers, audience)
 	if err != nil {
-		return nil, err
+		err = newVerificationError(idToken, err)
+		if v.Logger != nil {
+			logFailure(v.Logger, idToken, err)
+		}
 	}
+	if v.Anomalies != nil {
+		// the signature of expired tokens is not checked by default, see SignatureFirst
+		signed := errors.Is(err, ErrTokenUsedTooLate) && v.keySet().signed(ctx, idToken)
+		v.Anomalies.Observe(idToken, err, signed)
+	}
+	if v.Metrics != nil {
+		labels := map[string]string{"outcome": outcome(err)}
+		v.Metrics.IncCounter(MetricVerifications, labels)
+		v.Metrics.ObserveHistogram(MetricVerificationDuration, time.Since(start).Seconds(), labels)
+	}
+	return claimSet, err
+}
 
-	err = checkAudiences(claimSet, allowedAuds)
-	if err != nil {
-		return nil, err
+func (v *CertsVerifier) verifyIDToken(ctx context.Context, idToken string, issuers []string,
+	audience []string) (*ClaimSet, error) {
+	if len(audience) == 0 {
+		audience = v.DefaultAudience
 	}
+	checks := claimChecks{
+		audiences:    audience,
+		issuers:      issuers,
+		maxExpiry:    v.maxTokenLifetime(),
+		clockSkew:    v.clockSkew(),
+		now:          v.Clock,
+		allowExpired: v.AllowExpired,
+		algorithms:   v.Algorithms,
 
+		signatureFirst: v.SignatureFirst,
+		fastDecode:     v.FastDecode,
+
+		audienceMatchers: v.AudienceMatchers,
+	}
+	if len(v.PinnedKeyIDs) > 0 {
+		header, err := parseHeader(idToken)
+		if err != nil {
+			return nil, err
+		}
+		if !contains(v.PinnedKeyIDs, header.KeyID) {
+			return nil, fmt.Errorf("%w: kid %s is not pinned", ErrPublicKeyNotFound, header.KeyID)
+		}
+	}
+	claimSet, err := v.keySet().verify(ctx, idToken, checks, v.OnKeyMiss)
+	if claimSet == nil {
+		return nil, err
+	}
+	// err is ErrTokenExpired when the claims are returned in AllowExpired mode
+	expired := err
+	if len(v.RequiredClaims) > 0 {
+		err = CheckRequiredClaims(idToken, v.RequiredClaims)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if v.Assert != nil {
+		err = v.Assert(Claims(claimSet)).Err()
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, validate := range v.ClaimValidators {
+		err = validate(claimSet)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if v.PayloadValidator != nil {
+		payload, err := decodePayload(idToken)
+		if err != nil {
+			return nil, err
+		}
+		err = v.PayloadValidator.ValidatePayload(payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if expired != nil {
+		return claimSet, expired
+	}
+	if v.Provisioner != nil {
+		err = v.Provisioner.Ensure(claimSet)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if v.RevocationSampler != nil {
+		v.RevocationSampler.Sample(idToken, claimSet)
+	}
+	if v.OnExpiringSoon != nil {
+		if remaining := time.Unix(claimSet.Exp, 0).Sub(v.now()); remaining <= v.ExpiringSoon {
+			v.OnExpiringSoon(claimSet, remaining)
+		}
+	}
 	return claimSet, nil
 }
 
-func basicChecks(token string, certs *Certs, header *jws.Header, claimSet *ClaimSet, maxExpiry time.Duration) error {
-	key := certs.Keys[header.KeyID]
-	if key == nil {
+// ExpiresWithin reports whether the token of the claims expires within d
+func ExpiresWithin(claimSet *ClaimSet, d time.Duration) bool {
+	return time.Unix(claimSet.Exp, 0).Sub(nowFn()) <= d
+}
+
+// VerifySignedJWTWithCerts is golang port of OAuth2Client.prototype.verifySignedJwtWithCerts.
+// With options, e.g. WithNonce, the token is verified like by a CertsVerifier built with them.
+func VerifySignedJWTWithCerts(token string, certs *Certs, allowedAuds []string,
+	issuers []string, maxExpiry time.Duration, opts ...Option) (*ClaimSet, error) {
+	if len(opts) == 0 {
+		return NewKeySet(certs, nil).Verify(token, allowedAuds, issuers, maxExpiry)
+	}
+	opts = append([]Option{WithIssuers(issuers...), WithMaxTokenLifetime(maxExpiry)}, opts...)
+	return NewStaticVerifier(certs, opts...).VerifyIDToken(token, allowedAuds...)
+}
+
+// VerifySignatureOnlyWithCerts checks only the token signature against the given certs, see KeySet.VerifySignatureOnly
+func VerifySignatureOnlyWithCerts(token string, certs *Certs) (*ClaimSet, error) {
+	return NewKeySet(certs, nil).VerifySignatureOnly(token)
+}
+
+func checkSignature(token string, certs *Certs, header *jws.Header) error {
+	if !certs.HasKey(header.KeyID) {
 		return ErrPublicKeyNotFound
 	}
-	err := jws.Verify(token, key)
+	switch header.Algorithm {
+	case es256:
+		key := certs.ECKeys[header.KeyID]
+		if key == nil {
+			return ErrWrongSignature
+		}
+		return checkES256Signature(token, key)
+	case rs256:
+		key := certs.Keys[header.KeyID]
+		if key == nil {
+			return ErrWrongSignature
+		}
+		err := jws.Verify(token, key)
+		if err != nil {
+			return ErrWrongSignature
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, header.Algorithm)
+}
+
+// checkAlgorithm rejects the tokens whose alg is not one of algorithms, or else AllowedAlgorithms
+func checkAlgorithm(header *jws.Header, algorithms []string) error {
+	if len(algorithms) == 0 {
+		algorithms = AllowedAlgorithms
+	}
+	if !contains(algorithms, header.Algorithm) {
+		return fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, header.Algorithm)
+	}
+	return nil
+}
+
+// claimChecks is the policy applied by checkClaims
+type claimChecks struct {
+	audiences []string
+	issuers   []string
+	maxExpiry time.Duration
+	clockSkew time.Duration
+	// now tells the current time, nowFn when nil
+	now func() time.Time
+	// allowExpired makes checkClaims return ErrTokenUsedTooLate only once every other check passed
+	allowExpired bool
+	// algorithms are the accepted token algorithms, AllowedAlgorithms when empty
+	algorithms []string
+	// audienceMatchers accept the audiences not in audiences
+	audienceMatchers []func(aud string) bool
+	// signatureFirst makes KeySet.verify check the signature before the claims
+	signatureFirst bool
+	// fastDecode makes KeySet.verify decode the token with parseJWTFast
+	fastDecode bool
+}
+
+func defaultClaimChecks(audiences []string, issuers []string, maxExpiry time.Duration) claimChecks {
+	return claimChecks{audiences: audiences, issuers: issuers, maxExpiry: maxExpiry, clockSkew: ClockSkew}
+}
+
+func checkClaims(claimSet *ClaimSet, checks claimChecks) error {
+	now := nowFn
+	if checks.now != nil {
+		now = checks.now
+	}
+	var expired error
+	err := checkTimes(claimSet, now(), checks.maxExpiry, checks.clockSkew)
+	if err == ErrTokenUsedTooLate && checks.allowExpired {
+		expired = err
+	} else if err != nil {
+		return err
+	}
+
+	err = checkIssuer(claimSet, checks.issuers)
 	if err != nil {
-		return ErrWrongSignature
+		return err
 	}
+
+	err = checkAudiences(claimSet, checks.audiences, checks.audienceMatchers...)
+	if err != nil {
+		return err
+	}
+	return expired
+}
+
+func checkTimes(claimSet *ClaimSet, now time.Time, maxExpiry time.Duration, clockSkew time.Duration) error {
 	if claimSet.Iat < 1 {
 		return ErrNoIssueTimeInToken
 	}
 	if claimSet.Exp < 1 {
 		return ErrNoExpirationTimeInToken
 	}
-	now := nowFn()
 	if claimSet.Exp > now.Unix()+int64(maxExpiry.Seconds()) {
 		return ErrExpirationTimeTooFarInFuture
 	}
 
-	earliest := claimSet.Iat - int64(ClockSkew.Seconds())
-	latest := claimSet.Exp + int64(ClockSkew.Seconds())
+	earliest := claimSet.Iat - int64(clockSkew.Seconds())
+	latest := claimSet.Exp + int64(clockSkew.Seconds())
 
 	if now.Unix() < earliest {
 		return ErrTokenUsedTooEarly
@@ -116,23 +444,22 @@ func checkIssuer(claimSet *ClaimSet, issuers []string) error {
 	}
 
 	if !found {
-		return fmt.Errorf("wrong issuer: %s", claimSet.Iss)
+		return fmt.Errorf("%w: %s", ErrWrongIssuer, claimSet.Iss)
 	}
 
 	return nil
 }
 
-func checkAudiences(claimSet *ClaimSet, audiences []string) error {
-	audFound := false
-	for _, aud := range audiences {
-		if aud == claimSet.Aud {
-			audFound = true
-			break
+func checkAudiences(claimSet *ClaimSet, audiences []string, matchers ...func(aud string) bool) error {
+	for _, aud := range claimSet.audiences() {
+		if contains(audiences, aud) {
+			return nil
+		}
+		for _, match := range matchers {
+			if match(aud) {
+				return nil
+			}
 		}
 	}
-	if !audFound {
-		return fmt.Errorf("wrong aud: %s", claimSet.Aud)
-	}
-
-	return nil
-}
\ No newline at end of file
+	return fmt.Errorf("%w: %s", ErrWrongAudience, strings.Join(claimSet.audiences(), ","))
+}
@@ -1,6 +1,11 @@
 package googleIDVerifier
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -19,7 +24,8 @@ func (v *mockVerifier) VerifyIDToken(idToken string, audience ...string) error {
 	if err != nil {
 		return err
 	}
-	return VerifySignedJWTWithCerts(idToken, certs, audience, Issuers, MaxTokenLifetime)
+	_, err = VerifySignedJWTWithCerts(idToken, certs, audience, Issuers, MaxTokenLifetime)
+	return err
 }
 func TestParseJWT(t *testing.T) {
 	header, claimSet, _ := parseJWT(validTestToken)
@@ -35,11 +41,7 @@ func TestVerifier(t *testing.T) {
 	_, claimSet, _ := parseJWT(validTestToken)
 
 	v := mockVerifier{}
-	err := v.VerifyIDToken(wrongSigToken, claimSet.Aud)
-	if err != ErrWrongSignature {
-		t.Error("Expect ErrWrongSignature")
-	}
-	err = v.VerifyIDToken(validTestToken, claimSet.Aud)
+	err := v.VerifyIDToken(validTestToken, claimSet.Aud)
 	if err != nil && err != ErrTokenUsedTooLate {
 		t.Error(err)
 		t.Error("Expect ErrTokenUsedTooLate or actual valid token")
@@ -48,8 +50,12 @@ func TestVerifier(t *testing.T) {
 	nowFn = func() time.Time {
 		return time.Unix(claimSet.Exp, 0)
 	}
+	err = v.VerifyIDToken(wrongSigToken, claimSet.Aud)
+	if err != ErrWrongSignature {
+		t.Error("Expect ErrWrongSignature")
+	}
 	err = v.VerifyIDToken(validTestToken)
-	if !strings.Contains(err.Error(), "Wrong aud:") {
+	if !strings.Contains(err.Error(), "wrong aud:") {
 		t.Error("Expect wrong aud error")
 	}
 
@@ -60,3 +66,248 @@ func TestVerifier(t *testing.T) {
 
 	nowFn = time.Now
 }
+
+func TestCheckRequiredClaims(t *testing.T) {
+	err := CheckRequiredClaims(validTestToken, []ClaimRequirement{
+		RequiredClaim("email", ClaimString),
+		RequiredClaim("email_verified", ClaimBool),
+		OptionalClaim("hd", ClaimString),
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = CheckRequiredClaims(validTestToken, []ClaimRequirement{RequiredClaim("hd", ClaimString)})
+	if !errors.Is(err, ErrMissingClaim) || !strings.Contains(err.Error(), "hd") {
+		t.Errorf("Expect ErrMissingClaim naming hd, got %v", err)
+	}
+
+	err = CheckRequiredClaims(validTestToken, []ClaimRequirement{OptionalClaim("email", ClaimBool)})
+	if !errors.Is(err, ErrWrongClaimType) || !strings.Contains(err.Error(), "email") {
+		t.Errorf("Expect ErrWrongClaimType naming email, got %v", err)
+	}
+}
+
+func TestCheckOrder(t *testing.T) {
+	certs, _ := getTestCerts()
+	_, claimSet, _ := parseJWT(validTestToken)
+
+	// the test token is long expired, so claims checks fail before the signature is looked at
+	_, err := NewStaticVerifier(certs).VerifyIDToken(wrongSigToken, claimSet.Aud)
+	if !errors.Is(err, ErrTokenUsedTooLate) {
+		t.Errorf("Expect ErrTokenUsedTooLate, got %v", err)
+	}
+
+	_, err = NewStaticVerifier(certs, WithSignatureFirst()).VerifyIDToken(wrongSigToken, claimSet.Aud)
+	if !errors.Is(err, ErrWrongSignature) {
+		t.Errorf("Expect ErrWrongSignature, got %v", err)
+	}
+}
+
+func TestVerifySignatureOnly(t *testing.T) {
+	certs, _ := getTestCerts()
+
+	// the test token is long expired, which is ignored here
+	claimSet, err := VerifySignatureOnlyWithCerts(validTestToken, certs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claimSet.Email) == 0 {
+		t.Error("Invalid Email")
+	}
+
+	_, err = VerifySignatureOnlyWithCerts(wrongSigToken, certs)
+	if err != ErrWrongSignature {
+		t.Errorf("Expect ErrWrongSignature, got %v", err)
+	}
+}
+
+func TestInsecureDevVerifier(t *testing.T) {
+	_, claimSet, _ := parseJWT(validTestToken)
+	nowFn = func() time.Time {
+		return time.Unix(claimSet.Exp, 0)
+	}
+	defer func() { nowFn = time.Now }()
+
+	warnings := 0
+	v := NewInsecureDevVerifierDoNotUseInProduction(func(string, ...interface{}) { warnings++ })
+	_, err := v.VerifyIDToken(wrongSigToken, claimSet.Aud)
+	if err != nil {
+		t.Error(err)
+	}
+	if warnings != 2 {
+		t.Errorf("Expect a warning on creation and verification, got %d", warnings)
+	}
+
+	_, err = v.VerifyIDToken(wrongSigToken, "other")
+	if err == nil {
+		t.Error("Expect wrong aud error")
+	}
+
+	v = NewInsecureDevVerifierDoNotUseInProduction(func(string, ...interface{}) {}, WithAudiences(claimSet.Aud),
+		WithIssuers("http://localhost:9099"))
+	if _, err = v.VerifyIDToken(wrongSigToken); !errors.Is(err, ErrWrongIssuer) {
+		t.Errorf("Expect the issuers of the options to be checked, got %v", err)
+	}
+}
+
+func TestVerifyIDTokenWithIssuers(t *testing.T) {
+	certs, _ := getTestCerts()
+	defer func(keySet *KeySet) { googleKeySet = keySet }(googleKeySet)
+	googleKeySet = NewKeySet(certs, nil)
+	_, claimSet, _ := parseJWT(validTestToken)
+	nowFn = func() time.Time {
+		return time.Unix(claimSet.Exp, 0)
+	}
+	defer func() { nowFn = time.Now }()
+
+	v := &CertsVerifier{}
+	_, err := v.VerifyIDTokenWithIssuers(validTestToken, []string{"https://other.example.com"}, claimSet.Aud)
+	if !errors.Is(err, ErrWrongIssuer) {
+		t.Errorf("Expect ErrWrongIssuer, got %v", err)
+	}
+	_, err = v.VerifyIDTokenWithIssuers(validTestToken, []string{claimSet.Iss}, claimSet.Aud)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOnExpiringSoon(t *testing.T) {
+	certs, _ := getTestCerts()
+	defer func(keySet *KeySet) { googleKeySet = keySet }(googleKeySet)
+	googleKeySet = NewKeySet(certs, nil)
+	_, claimSet, _ := parseJWT(validTestToken)
+	defer func() { nowFn = time.Now }()
+
+	var remaining []time.Duration
+	v := &CertsVerifier{ExpiringSoon: 5 * time.Minute, OnExpiringSoon: func(_ *ClaimSet, r time.Duration) {
+		remaining = append(remaining, r)
+	}}
+	for _, before := range []time.Duration{time.Hour / 2, time.Minute} {
+		nowFn = func() time.Time {
+			return time.Unix(claimSet.Exp, 0).Add(-before)
+		}
+		if _, err := v.VerifyIDToken(validTestToken, claimSet.Aud); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(remaining) != 1 || remaining[0] != time.Minute {
+		t.Errorf("Expect one signal with a minute remaining, got %v", remaining)
+	}
+
+	// the verifier clock applies rather than the system one, by which the token has long expired
+	nowFn = time.Now
+	remaining = nil
+	v = NewStaticVerifier(certs, WithClock(func() time.Time { return time.Unix(claimSet.Exp, 0).Add(-time.Hour / 2) }))
+	v.ExpiringSoon = 5 * time.Minute
+	v.OnExpiringSoon = func(_ *ClaimSet, r time.Duration) { remaining = append(remaining, r) }
+	if _, err := v.VerifyIDToken(validTestToken, claimSet.Aud); err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 0 {
+		t.Errorf("Expect no signal half an hour before the expiry by the verifier clock, got %v", remaining)
+	}
+}
+
+func TestVerifyIDTokenContext(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	defer func(url string) {
+		googleOAuth2FederatedSignOnCertsURL = url
+		googleKeySet = NewGoogleKeySet()
+	}(googleOAuth2FederatedSignOnCertsURL)
+	googleOAuth2FederatedSignOnCertsURL = srv.URL
+	googleKeySet = NewGoogleKeySet()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := (&CertsVerifier{}).VerifyIDTokenContext(ctx, validTestToken, "407408718192.apps.googleusercontent.com")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expect the certs fetch to be aborted, got %v", err)
+	}
+}
+
+func TestClaimSetRaw(t *testing.T) {
+	claimSet, err := Decode(validTestToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimSet.AuthorizedParty != "407408718192.apps.googleusercontent.com" {
+		t.Errorf("Expect azp to be decoded, got %q", claimSet.AuthorizedParty)
+	}
+	if raw := claimSet.Raw(); raw["at_hash"] != "ZZWtt-W4EWXvT6RTXfDaRQ" || raw["email"] != claimSet.Email {
+		t.Errorf("Expect raw claims, got %v", raw)
+	}
+	if (&ClaimSet{}).Raw() != nil {
+		t.Error("Expect no raw claims for a ClaimSet not decoded from a token")
+	}
+}
+
+type countingTransport struct {
+	requests int
+}
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.requests++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestHTTPClient(t *testing.T) {
+	certs, _ := getTestCerts()
+	jwks, _ := certs.MarshalJSON()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(jwks)
+	}))
+	defer srv.Close()
+	defer func(url string) { googleOAuth2FederatedSignOnCertsURL = url }(googleOAuth2FederatedSignOnCertsURL)
+	googleOAuth2FederatedSignOnCertsURL = srv.URL
+
+	_, claimSet, _ := parseJWT(validTestToken)
+	nowFn = func() time.Time { return time.Unix(claimSet.Exp, 0) }
+	defer func() { nowFn = time.Now }()
+
+	transport := &countingTransport{}
+	v := &CertsVerifier{HTTPClient: &http.Client{Transport: transport}}
+	_, err := v.VerifyIDToken(validTestToken, claimSet.Aud)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transport.requests != 1 {
+		t.Errorf("Expect certs to be fetched with the verifier client, got %d requests", transport.requests)
+	}
+}
+
+func TestAllowedAlgorithms(t *testing.T) {
+	certs, _ := getTestCerts()
+	_, claimSet, _ := parseJWT(validTestToken)
+	nowFn = func() time.Time { return time.Unix(claimSet.Exp, 0) }
+	defer func() { nowFn = time.Now }()
+
+	s := strings.Split(validTestToken, ".")
+	withAlg := func(alg string, sig string) string {
+		header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"` + alg + `","kid":"3f3ef9c7803cd0b8d75247ee0d31fdd5c2cf3812"}`))
+		return header + "." + s[1] + "." + sig
+	}
+	v := NewStaticVerifier(certs)
+	for _, token := range []string{withAlg("none", ""), withAlg("HS256", s[2]), withAlg("RS512", s[2])} {
+		_, err := v.VerifyIDToken(token, claimSet.Aud)
+		if !errors.Is(err, ErrUnsupportedAlgorithm) || FailureReasonOf(err) != FailureBadSignature {
+			t.Errorf("Expect ErrUnsupportedAlgorithm, got %v", err)
+		}
+		if _, err = VerifySignatureOnlyWithCerts(token, certs); !errors.Is(err, ErrUnsupportedAlgorithm) {
+			t.Errorf("Expect ErrUnsupportedAlgorithm verifying the signature only, got %v", err)
+		}
+	}
+
+	if _, err := NewStaticVerifier(certs, WithAllowedAlgorithms("ES256")).VerifyIDToken(validTestToken, claimSet.Aud); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("Expect RS256 rejected when not allowed, got %v", err)
+	}
+	if _, err := NewStaticVerifier(certs, WithAllowedAlgorithms("RS256")).VerifyIDToken(validTestToken, claimSet.Aud); err != nil {
+		t.Error(err)
+	}
+}